@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// WithContext returns c as ContextCache.
+// If c doesn't support context natively, ctx is only passed to the miss cache function.
+func WithContext(c Cache) ContextCache {
+	if cc, ok := c.(ContextCache); ok {
+		return cc
+	}
+	if a, ok := c.(*backgroundAdapter); ok {
+		return a.c
+	}
+
+	return &contextAdapter{c: c}
+}
+
+// WithoutContext returns c as Cache, all calls use context.Background()
+func WithoutContext(c ContextCache) Cache {
+	if cc, ok := c.(Cache); ok {
+		return cc
+	}
+	if a, ok := c.(*contextAdapter); ok {
+		return a.c
+	}
+
+	return &backgroundAdapter{c: c}
+}
+
+type contextAdapter struct {
+	c Cache
+}
+
+func (a *contextAdapter) SetCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+	return a.c.Set(key, data, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) GetCtx(ctx context.Context, key string, ptr interface{}, fn MissCacheCtxFn) error {
+	if fn == nil {
+		return a.c.Get(key, ptr, nil)
+	}
+
+	return a.c.Get(key, ptr, func() error {
+		return fn(ctx)
+	})
+}
+
+func (a *contextAdapter) LookupCtx(ctx context.Context, key string, ptr interface{}) (bool, error) {
+	return a.c.Lookup(key, ptr)
+}
+
+func (a *contextAdapter) GetOrLoadCtx(ctx context.Context, key string, ptr interface{}, ttl time.Duration, loader LoadCtxFn) error {
+	return a.c.GetOrLoad(key, ptr, ToSeconds(ttl), func() (interface{}, error) {
+		return loader(ctx)
+	})
+}
+
+func (a *contextAdapter) DeleteCtx(ctx context.Context, key string) (bool, error) {
+	return a.c.Delete(key)
+}
+
+func (a *contextAdapter) IsExistCtx(ctx context.Context, key string) (bool, error) {
+	return a.c.IsExist(key)
+}
+
+func (a *contextAdapter) GetManyCtx(ctx context.Context, keys []string, factory PtrFactory) (map[string]interface{}, error) {
+	return a.c.GetMany(keys, factory)
+}
+
+func (a *contextAdapter) GetManyOrLoadCtx(ctx context.Context, keys []string, factory PtrFactory, ttl time.Duration, loader LoadManyCtxFn) (map[string]interface{}, error) {
+	return a.c.GetManyOrLoad(keys, factory, ToSeconds(ttl), func(keys []string) (map[string]interface{}, error) {
+		return loader(ctx, keys)
+	})
+}
+
+func (a *contextAdapter) SetManyCtx(ctx context.Context, items map[string]interface{}, ttl time.Duration) error {
+	return a.c.SetMany(items, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) DeleteManyCtx(ctx context.Context, keys []string) (int, error) {
+	return a.c.DeleteMany(keys)
+}
+
+func (a *contextAdapter) DeleteByPrefixCtx(ctx context.Context, prefix string) (int, error) {
+	return a.c.DeleteByPrefix(prefix)
+}
+
+func (a *contextAdapter) KeysCtx(ctx context.Context, prefix string) Iterator {
+	return a.c.Keys(prefix)
+}
+
+func (a *contextAdapter) TTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	return a.c.TTL(key)
+}
+
+func (a *contextAdapter) TouchCtx(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return a.c.Touch(key, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) IncrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return a.c.Incr(key, delta, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) DecrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return a.c.Decr(key, delta, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) SetNXCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (bool, error) {
+	return a.c.SetNX(key, data, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) CompareAndSwapCtx(ctx context.Context, key string, old, new interface{}, ttl time.Duration) (bool, error) {
+	return a.c.CompareAndSwap(key, old, new, ToSeconds(ttl))
+}
+
+func (a *contextAdapter) FlushCtx(ctx context.Context) (int, error) {
+	return a.c.Flush()
+}
+
+func (a *contextAdapter) IsReadyCtx(ctx context.Context) bool {
+	return a.c.IsReady()
+}
+
+func (a *contextAdapter) IsEnable() bool {
+	return a.c.IsEnable()
+}
+
+func (a *contextAdapter) Close() error {
+	return a.c.Close()
+}
+
+type backgroundAdapter struct {
+	c ContextCache
+}
+
+func (a *backgroundAdapter) Set(key string, data interface{}, ttl int) error {
+	return a.c.SetCtx(context.Background(), key, data, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) Get(key string, ptr interface{}, fn MissCacheFn) error {
+	return a.c.GetCtx(context.Background(), key, ptr, fn.WithContext())
+}
+
+func (a *backgroundAdapter) Lookup(key string, ptr interface{}) (bool, error) {
+	return a.c.LookupCtx(context.Background(), key, ptr)
+}
+
+func (a *backgroundAdapter) GetOrLoad(key string, ptr interface{}, ttl int, loader LoadFn) error {
+	return a.c.GetOrLoadCtx(context.Background(), key, ptr, Seconds(ttl), loader.WithContext())
+}
+
+func (a *backgroundAdapter) Delete(key string) (bool, error) {
+	return a.c.DeleteCtx(context.Background(), key)
+}
+
+func (a *backgroundAdapter) IsExist(key string) (bool, error) {
+	return a.c.IsExistCtx(context.Background(), key)
+}
+
+func (a *backgroundAdapter) GetMany(keys []string, factory PtrFactory) (map[string]interface{}, error) {
+	return a.c.GetManyCtx(context.Background(), keys, factory)
+}
+
+func (a *backgroundAdapter) GetManyOrLoad(keys []string, factory PtrFactory, ttl int, loader LoadManyFn) (map[string]interface{}, error) {
+	return a.c.GetManyOrLoadCtx(context.Background(), keys, factory, Seconds(ttl), loader.WithContext())
+}
+
+func (a *backgroundAdapter) SetMany(items map[string]interface{}, ttl int) error {
+	return a.c.SetManyCtx(context.Background(), items, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) DeleteMany(keys []string) (int, error) {
+	return a.c.DeleteManyCtx(context.Background(), keys)
+}
+
+func (a *backgroundAdapter) DeleteByPrefix(prefix string) (int, error) {
+	return a.c.DeleteByPrefixCtx(context.Background(), prefix)
+}
+
+func (a *backgroundAdapter) Keys(prefix string) Iterator {
+	return a.c.KeysCtx(context.Background(), prefix)
+}
+
+func (a *backgroundAdapter) TTL(key string) (time.Duration, error) {
+	return a.c.TTLCtx(context.Background(), key)
+}
+
+func (a *backgroundAdapter) Touch(key string, ttl int) (bool, error) {
+	return a.c.TouchCtx(context.Background(), key, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) Incr(key string, delta int64, ttl int) (int64, error) {
+	return a.c.IncrCtx(context.Background(), key, delta, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) Decr(key string, delta int64, ttl int) (int64, error) {
+	return a.c.DecrCtx(context.Background(), key, delta, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) SetNX(key string, data interface{}, ttl int) (bool, error) {
+	return a.c.SetNXCtx(context.Background(), key, data, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) CompareAndSwap(key string, old, new interface{}, ttl int) (bool, error) {
+	return a.c.CompareAndSwapCtx(context.Background(), key, old, new, Seconds(ttl))
+}
+
+func (a *backgroundAdapter) Flush() (int, error) {
+	return a.c.FlushCtx(context.Background())
+}
+
+func (a *backgroundAdapter) IsReady() bool {
+	return a.c.IsReadyCtx(context.Background())
+}
+
+func (a *backgroundAdapter) IsEnable() bool {
+	return a.c.IsEnable()
+}
+
+func (a *backgroundAdapter) Close() error {
+	return a.c.Close()
+}
@@ -0,0 +1,295 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapCache is a minimal Cache implementation for testing adapters
+type mapCache map[string][]byte
+
+func (m mapCache) Set(key string, data interface{}, ttl int) (err error) {
+	m[key], err = json.Marshal(data)
+	return err
+}
+
+func (m mapCache) Get(key string, ptr interface{}, fn MissCacheFn) error {
+	found, err := m.Lookup(key, ptr)
+	if err != nil || found || fn == nil {
+		return err
+	}
+
+	return fn()
+}
+
+func (m mapCache) Lookup(key string, ptr interface{}) (bool, error) {
+	v, ok := m[key]
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(v, ptr)
+}
+
+func (m mapCache) GetOrLoad(key string, ptr interface{}, ttl int, loader LoadFn) error {
+	found, err := m.Lookup(key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	v, err := loader()
+	if err != nil {
+		return err
+	}
+	if err = m.Set(key, v, ttl); err != nil {
+		return err
+	}
+
+	_, err = m.Lookup(key, ptr)
+	return err
+}
+
+func (m mapCache) Delete(key string) (bool, error) {
+	_, ok := m[key]
+	delete(m, key)
+	return ok, nil
+}
+
+func (m mapCache) IsExist(key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func (m mapCache) GetMany(keys []string, factory PtrFactory) (map[string]interface{}, error) {
+	found := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		ptr := factory()
+		ok, err := m.Lookup(key, ptr)
+		if err != nil {
+			return found, err
+		}
+		if ok {
+			found[key] = ptr
+		}
+	}
+	return found, nil
+}
+
+func (m mapCache) GetManyOrLoad(keys []string, factory PtrFactory, ttl int, loader LoadManyFn) (map[string]interface{}, error) {
+	found, err := m.GetMany(keys, factory)
+	if err != nil {
+		return found, err
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	values, err := loader(missing)
+	if err != nil {
+		return found, err
+	}
+	if err = m.SetMany(values, ttl); err != nil {
+		return found, err
+	}
+
+	return m.GetMany(keys, factory)
+}
+
+func (m mapCache) SetMany(items map[string]interface{}, ttl int) error {
+	for key, data := range items {
+		if err := m.Set(key, data, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m mapCache) DeleteMany(keys []string) (int, error) {
+	count := 0
+	for _, key := range keys {
+		if ok, _ := m.Delete(key); ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m mapCache) DeleteByPrefix(prefix string) (int, error) {
+	count := 0
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			delete(m, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m mapCache) Keys(prefix string) Iterator {
+	it := &sliceIterator{}
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			it.keys = append(it.keys, key)
+		}
+	}
+	return it
+}
+
+func (m mapCache) TTL(key string) (time.Duration, error) {
+	if _, ok := m[key]; !ok {
+		return 0, ErrMiss
+	}
+	return 0, nil
+}
+
+func (m mapCache) Touch(key string, ttl int) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func (m mapCache) Incr(key string, delta int64, ttl int) (int64, error) {
+	var value int64
+	if _, err := m.Lookup(key, &value); err != nil {
+		return 0, err
+	}
+	value += delta
+	return value, m.Set(key, value, ttl)
+}
+
+func (m mapCache) Decr(key string, delta int64, ttl int) (int64, error) {
+	return m.Incr(key, -delta, ttl)
+}
+
+func (m mapCache) SetNX(key string, data interface{}, ttl int) (bool, error) {
+	if _, ok := m[key]; ok {
+		return false, nil
+	}
+	return true, m.Set(key, data, ttl)
+}
+
+func (m mapCache) CompareAndSwap(key string, old, new interface{}, ttl int) (bool, error) {
+	b, err := json.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	if v, ok := m[key]; !ok || string(v) != string(b) {
+		return false, nil
+	}
+	return true, m.Set(key, new, ttl)
+}
+
+func (m mapCache) Flush() (int, error) {
+	count := len(m)
+	for key := range m {
+		delete(m, key)
+	}
+	return count, nil
+}
+
+func (m mapCache) IsReady() bool  { return true }
+func (m mapCache) IsEnable() bool { return true }
+func (m mapCache) Close() error   { return nil }
+
+// sliceIterator iterates keys of mapCache
+type sliceIterator struct {
+	keys []string
+	key  string
+}
+
+func (it *sliceIterator) Next() bool {
+	if len(it.keys) == 0 {
+		return false
+	}
+	it.key, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+func (it *sliceIterator) Key() string { return it.key }
+func (it *sliceIterator) Err() error  { return nil }
+
+type ctxKey struct{}
+
+func TestWithContext(t *testing.T) {
+	cc := WithContext(mapCache{})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// Context is passed to miss cache function
+	err := cc.GetCtx(ctx, "key", nil, func(ctx context.Context) error {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return cc.SetCtx(ctx, "key", 1, 0)
+	})
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	err = cc.GetCtx(ctx, "key", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Adapter is converted back to the original cache
+	c := WithoutContext(cc)
+	assert.IsType(t, mapCache{}, c)
+
+	count, err := c.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestWithoutContext(t *testing.T) {
+	c := WithoutContext(WithContext(mapCache{}))
+	assert.IsType(t, mapCache{}, c)
+
+	// Context only cache is adapted
+	c = WithoutContext(struct{ ContextCache }{WithContext(mapCache{})})
+	assert.IsType(t, &backgroundAdapter{}, c)
+
+	err := c.Set("key", 1, 0)
+	assert.Nil(t, err)
+
+	ok, err := c.IsExist("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	cacheInt := 0
+	found, err := c.Lookup("key", &cacheInt)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, cacheInt)
+
+	err = c.GetOrLoad("key:load", &cacheInt, 0, func() (interface{}, error) {
+		return 2, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cacheInt)
+
+	err = c.Get("key:missing", nil, func() error {
+		return NotSupportedErr
+	})
+	assert.Equal(t, NotSupportedErr, err)
+
+	// Batch operations are adapted
+	values, err := c.GetManyOrLoad([]string{"key", "key:many"}, func() interface{} { return new(int) }, 0, func(keys []string) (map[string]interface{}, error) {
+		assert.Equal(t, []string{"key:many"}, keys)
+		return map[string]interface{}{"key:many": 3}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, *values["key"].(*int))
+	assert.Equal(t, 3, *values["key:many"].(*int))
+
+	it := c.Keys("key:m")
+	assert.True(t, it.Next())
+	assert.Equal(t, "key:many", it.Key())
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+
+	count, err := c.DeleteMany([]string{"key", "key:many", "key:missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}
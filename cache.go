@@ -1,12 +1,48 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type MissCacheFn func() error
 
+// MissCacheCtxFn is the context-aware variant of MissCacheFn, it receives the ctx passed to GetCtx
+type MissCacheCtxFn func(ctx context.Context) error
+
+// LoadFn loads the value of a missing cache
+type LoadFn func() (interface{}, error)
+
+// LoadCtxFn is the context-aware variant of LoadFn, it receives the ctx passed to GetOrLoadCtx
+type LoadCtxFn func(ctx context.Context) (interface{}, error)
+
+// PtrFactory returns a new pointer to decode a cached value into, e.g. func() interface{} { return new(Data) }
+type PtrFactory func() interface{}
+
+// LoadManyFn loads the values of missing keys at once, keys which are not found are omitted
+type LoadManyFn func(keys []string) (map[string]interface{}, error)
+
+// LoadManyCtxFn is the context-aware variant of LoadManyFn
+type LoadManyCtxFn func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// Iterator iterates keys, e.g.
+//
+//	it := c.Keys("user:")
+//	for it.Next() {
+//		fmt.Println(it.Key())
+//	}
+//	err := it.Err()
+type Iterator interface {
+	// Next advances to the next key, false at the end or on error
+	Next() bool
+	// Key returns the current key
+	Key() string
+	// Err returns the error which stopped the iteration
+	Err() error
+}
+
 var NotSupportedErr = fmt.Errorf("not suppported")
 
 type Cache interface {
@@ -14,14 +50,103 @@ type Cache interface {
 	// ttl=0 : no expire
 	Set(key string, data interface{}, ttl int) (err error)
 	Get(key string, ptr interface{}, fn MissCacheFn) (err error)
+	// Lookup decodes cached value into ptr, found=false on cache miss and ptr is untouched
+	Lookup(key string, ptr interface{}) (found bool, err error)
+	// GetOrLoad decodes cached value into ptr, on cache miss the value is loaded, decoded into ptr and cached with ttl.
+	// Without loader, ErrMiss is returned on cache miss.
+	GetOrLoad(key string, ptr interface{}, ttl int, loader LoadFn) (err error)
 	Delete(key string) (ok bool, err error)
 	IsExist(key string) (ok bool, err error)
+	// GetMany decodes cached values of keys into new pointers of factory, found values are returned by key
+	GetMany(keys []string, factory PtrFactory) (found map[string]interface{}, err error)
+	// GetManyOrLoad is GetMany, missing values are loaded at once by loader and cached with ttl
+	GetManyOrLoad(keys []string, factory PtrFactory, ttl int, loader LoadManyFn) (found map[string]interface{}, err error)
+	SetMany(items map[string]interface{}, ttl int) (err error)
+	DeleteMany(keys []string) (count int, err error)
+	// DeleteByPrefix deletes keys starting with prefix, e.g. MakeKey("user", id, ""), count is the number of deleted keys
+	DeleteByPrefix(prefix string) (count int, err error)
+	// Keys iterates cached keys starting with prefix in no particular order, for debugging and migrations
+	Keys(prefix string) Iterator
+	// TTL returns the remaining TTL of key, 0 if it never expires, ErrMiss if it is not found
+	TTL(key string) (ttl time.Duration, err error)
+	// Touch resets TTL of key without rewriting its value, ok=false if it is not found
+	Touch(key string, ttl int) (ok bool, err error)
+	// Incr increments the counter of key by delta atomically, a missing counter is created with ttl which is not extended by later increments
+	Incr(key string, delta int64, ttl int) (value int64, err error)
+	// Decr decrements the counter of key by delta atomically, like Incr
+	Decr(key string, delta int64, ttl int) (value int64, err error)
+	// SetNX sets data only if key is missing, ok=false if it exists
+	SetNX(key string, data interface{}, ttl int) (ok bool, err error)
+	// CompareAndSwap sets new only if the cached value equals old by their encoding, ok=false if it is missing or different
+	CompareAndSwap(key string, old, new interface{}, ttl int) (ok bool, err error)
 	Flush() (count int, err error)
 	IsReady() (ok bool)
 	IsEnable() (ok bool)
 	Close() (err error)
 }
 
+// ContextCache is the context-first variant of Cache, ctx deadline and cancellation are passed to the cache backend.
+// Its TTLs are time.Duration, DefaultTTL uses the default TTL of the cache config and NoExpiration never expires.
+type ContextCache interface {
+	// ttl=DefaultTTL: will use default TTL
+	// ttl=NoExpiration: no expire
+	SetCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (err error)
+	GetCtx(ctx context.Context, key string, ptr interface{}, fn MissCacheCtxFn) (err error)
+	LookupCtx(ctx context.Context, key string, ptr interface{}) (found bool, err error)
+	GetOrLoadCtx(ctx context.Context, key string, ptr interface{}, ttl time.Duration, loader LoadCtxFn) (err error)
+	DeleteCtx(ctx context.Context, key string) (ok bool, err error)
+	IsExistCtx(ctx context.Context, key string) (ok bool, err error)
+	GetManyCtx(ctx context.Context, keys []string, factory PtrFactory) (found map[string]interface{}, err error)
+	GetManyOrLoadCtx(ctx context.Context, keys []string, factory PtrFactory, ttl time.Duration, loader LoadManyCtxFn) (found map[string]interface{}, err error)
+	SetManyCtx(ctx context.Context, items map[string]interface{}, ttl time.Duration) (err error)
+	DeleteManyCtx(ctx context.Context, keys []string) (count int, err error)
+	DeleteByPrefixCtx(ctx context.Context, prefix string) (count int, err error)
+	KeysCtx(ctx context.Context, prefix string) Iterator
+	TTLCtx(ctx context.Context, key string) (ttl time.Duration, err error)
+	TouchCtx(ctx context.Context, key string, ttl time.Duration) (ok bool, err error)
+	IncrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error)
+	DecrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error)
+	SetNXCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (ok bool, err error)
+	CompareAndSwapCtx(ctx context.Context, key string, old, new interface{}, ttl time.Duration) (ok bool, err error)
+	FlushCtx(ctx context.Context) (count int, err error)
+	IsReadyCtx(ctx context.Context) (ok bool)
+	IsEnable() (ok bool)
+	Close() (err error)
+}
+
+// WithContext converts fn to MissCacheCtxFn, nil fn stays nil
+func (fn MissCacheFn) WithContext() MissCacheCtxFn {
+	if fn == nil {
+		return nil
+	}
+
+	return func(context.Context) error {
+		return fn()
+	}
+}
+
+// WithContext converts fn to LoadCtxFn, nil fn stays nil
+func (fn LoadFn) WithContext() LoadCtxFn {
+	if fn == nil {
+		return nil
+	}
+
+	return func(context.Context) (interface{}, error) {
+		return fn()
+	}
+}
+
+// WithContext converts fn to LoadManyCtxFn, nil fn stays nil
+func (fn LoadManyFn) WithContext() LoadManyCtxFn {
+	if fn == nil {
+		return nil
+	}
+
+	return func(_ context.Context, keys []string) (map[string]interface{}, error) {
+		return fn(keys)
+	}
+}
+
 func MakeKey(parts ...string) string {
 	return strings.Join(parts, ":")
 }
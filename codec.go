@@ -0,0 +1,7 @@
+package cache
+
+// Codec encodes cached values to bytes and decodes them back, implementations are in the codec package
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, ptr interface{}) error
+}
@@ -0,0 +1,18 @@
+// Package codec provides cache.Codec implementations for local and redis caches
+package codec
+
+import "github.com/hoaitan/cache"
+
+var (
+	// Gob codec, default of local cache
+	Gob cache.Codec = gobCodec{}
+
+	// JSON codec, default of redis cache
+	JSON cache.Codec = jsonCodec{}
+
+	// Msgpack codec
+	Msgpack cache.Codec = msgpackCodec{}
+
+	// Proto codec, values must implement proto.Message
+	Proto cache.Codec = protoCodec{}
+)
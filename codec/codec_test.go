@@ -0,0 +1,216 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestGob(t *testing.T) {
+	var (
+		// Bool
+		boolData, boolData2 bool = true, false
+
+		// String
+		stringData, stringData2 string = "test", ""
+
+		// Int
+		intData, intData2     int   = 1, 0
+		int8Data, int8Data2   int8  = 1, 0
+		int16Data, int16Data2 int32 = 1, 0
+		int32Data, int32Data2 int32 = 1, 0
+		int64Data, int64Data2 int64 = 1, 0
+
+		uintData, uintData2     uint   = 1, 0
+		uint8Data, uint8Data2   uint8  = 1, 0
+		uint16Data, uint16Data2 uint32 = 1, 0
+		uint32Data, uint32Data2 uint32 = 1, 0
+		uint64Data, uint64Data2 uint64 = 1, 0
+
+		byteData, byteData2 byte = 1, 0
+		runeData, runeData2 rune = 1, 0
+
+		// Float
+		float32Data, float32Data2 float32 = 1, 0
+		float64Data, float64Data2 float64 = 1, 0
+
+		// Complex
+		complex64Data, complex64Data2   complex64  = 1, 0
+		complex128Data, complex128Data2 complex128 = 1, 0
+
+		// Slice
+		sliceData, sliceData2 []int = []int{1}, []int{0}
+
+		// Map
+		mapData, mapData2 map[string]int = map[string]int{"test": 1}, map[string]int{"test": 0}
+
+		// Struct & Pointer
+	)
+	tests := []struct {
+		data              interface{}
+		ptrToSameDataType interface{}
+	}{
+		{
+			boolData,
+			&boolData2,
+		},
+		{
+			stringData,
+			&stringData2,
+		},
+		// Int
+		{
+			intData,
+			&intData2,
+		},
+		{
+			int8Data,
+			&int8Data2,
+		},
+		{
+			int16Data,
+			&int16Data2,
+		},
+		{
+			int32Data,
+			&int32Data2,
+		},
+		{
+			int64Data,
+			&int64Data2,
+		},
+		{
+			uintData,
+			&uintData2,
+		},
+		{
+			uint8Data,
+			&uint8Data2,
+		},
+		{
+			uint16Data,
+			&uint16Data2,
+		},
+		{
+			uint32Data,
+			&uint32Data2,
+		},
+		{
+			uint64Data,
+			&uint64Data2,
+		},
+		{
+			byteData,
+			&byteData2,
+		},
+		{
+			runeData,
+			&runeData2,
+		},
+
+		// Float
+		{
+			float32Data,
+			&float32Data2,
+		},
+		{
+			float64Data,
+			&float64Data2,
+		},
+
+		// Complex
+		{
+			complex64Data,
+			&complex64Data2,
+		},
+		{
+			complex128Data,
+			&complex128Data2,
+		},
+
+		// Slice
+		{
+			sliceData,
+			&sliceData2,
+		},
+
+		// Map
+		{
+			mapData,
+			&mapData2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("With type=%T", tt.data), func(t *testing.T) {
+			// Set
+			b, err := Gob.Marshal(tt.data)
+			assert.Nil(t, err)
+
+			// Get
+			err = Gob.Unmarshal(b, tt.ptrToSameDataType)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.data, reflect.Indirect(reflect.ValueOf(tt.ptrToSameDataType)).Interface())
+		})
+	}
+}
+
+type codecData struct {
+	Number int
+	Text   string
+	Slice  []int
+	Map    map[string]int
+}
+
+func TestCodecs(t *testing.T) {
+	codecs := map[string]cache.Codec{
+		"json":    JSON,
+		"msgpack": Msgpack,
+		"gob":     Gob,
+	}
+	data := codecData{
+		Number: 1,
+		Text:   "test",
+		Slice:  []int{1, 2},
+		Map:    map[string]int{"test": 1},
+	}
+
+	for name, c := range codecs {
+		t.Run(name, func(t *testing.T) {
+			b, err := c.Marshal(data)
+			assert.Nil(t, err)
+
+			// Same type
+			cachedData := codecData{}
+			err = c.Unmarshal(b, &cachedData)
+			assert.Nil(t, err)
+			assert.Equal(t, data, cachedData)
+
+			// Invalid type
+			cachedInt := 0
+			err = c.Unmarshal(b, &cachedInt)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProto(t *testing.T) {
+	b, err := Proto.Marshal(wrapperspb.String("test"))
+	assert.Nil(t, err)
+
+	cachedData := &wrapperspb.StringValue{}
+	err = Proto.Unmarshal(b, cachedData)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", cachedData.GetValue())
+
+	// Not a proto message
+	_, err = Proto.Marshal(1)
+	assert.Error(t, err)
+
+	cachedInt := 0
+	err = Proto.Unmarshal(b, &cachedInt)
+	assert.Error(t, err)
+}
@@ -0,0 +1,24 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	buff := new(bytes.Buffer)
+	enc := gob.NewEncoder(buff)
+
+	err := enc.Encode(v)
+	return buff.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, ptr interface{}) error {
+	buff := bytes.NewBuffer(data)
+	dec := gob.NewDecoder(buff)
+
+	err := dec.Decode(ptr)
+	return err
+}
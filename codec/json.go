@@ -0,0 +1,13 @@
+package codec
+
+import "encoding/json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return json.Unmarshal(data, ptr)
+}
@@ -0,0 +1,13 @@
+package codec
+
+import "github.com/vmihailenco/msgpack/v5"
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return msgpack.Unmarshal(data, ptr)
+}
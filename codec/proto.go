@@ -0,0 +1,27 @@
+package codec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type protoCodec struct{}
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T is not a proto.Message", v)
+	}
+
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, ptr interface{}) error {
+	m, ok := ptr.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto codec: %T is not a proto.Message", ptr)
+	}
+
+	return proto.Unmarshal(data, m)
+}
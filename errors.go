@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMiss is returned when the key is not found in cache
+var ErrMiss = errors.New("cache miss")
+
+// BackendError is a failure of the cache backend itself (timeout, connection refused, auth failure...),
+// it is never returned for a cache miss
+type BackendError struct {
+	Backend string
+	Err     error
+}
+
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Backend, e.Err)
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// IsBackendError reports whether err is caused by a cache backend failure
+func IsBackendError(err error) bool {
+	var backendErr *BackendError
+	return errors.As(err, &backendErr)
+}
+
+// ErrorPolicy controls how Get handles backend errors
+type ErrorPolicy int
+
+const (
+	// FailOnError returns the backend error to the caller (default)
+	FailOnError ErrorPolicy = iota
+
+	// LoadOnError treats the backend error as a cache miss and calls the miss cache function
+	LoadOnError
+
+	// NextLayerOnError serves from the next layer of a multi cache, the last layer falls through to the miss cache function
+	NextLayerOnError
+)
@@ -1,62 +1,90 @@
 package id
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/coalesce"
 )
 
-const defaultTTL = 24 * 3600 // Long TTL: 1 day
+const defaultTTL = 24 * time.Hour // Long TTL: 1 day
 
 type Cache interface {
 	SetLoadFn(loadFn func(name string) (id string, err error)) Cache
+	SetLoadCtxFn(loadFn func(ctx context.Context, name string) (id string, err error)) Cache
 	GetOrSet(name string) (id string, err error)
+	GetOrSetCtx(ctx context.Context, name string) (id string, err error)
 	IsExist(name string) (ok bool, err error)
+	IsExistCtx(ctx context.Context, name string) (ok bool, err error)
 	Delete(name string) (err error)
+	DeleteCtx(ctx context.Context, name string) (err error)
 }
 
 type idCache struct {
-	cache     cache.Cache
+	cache     cache.ContextCache
 	namespace string
-	loadFn    func(name string) (id string, err error)
+	loadFn    func(ctx context.Context, name string) (id string, err error)
+	group     *coalesce.Group
 }
 
-func New(cache cache.Cache, namespace string) Cache {
+func New(c cache.Cache, namespace string) Cache {
 	return &idCache{
-		cache:     cache,
+		cache:     cache.WithContext(c),
 		namespace: namespace,
+		group:     coalesce.New(true),
 	}
 }
 
 func (c *idCache) SetLoadFn(loadFn func(name string) (id string, err error)) Cache {
+	if loadFn == nil {
+		c.loadFn = nil
+		return c
+	}
+
+	return c.SetLoadCtxFn(func(_ context.Context, name string) (id string, err error) {
+		return loadFn(name)
+	})
+}
+
+func (c *idCache) SetLoadCtxFn(loadFn func(ctx context.Context, name string) (id string, err error)) Cache {
 	c.loadFn = loadFn
 	return c
 }
 
 func (c *idCache) GetOrSet(name string) (id string, err error) {
-	err = c.cache.Get(cache.MakeKey(c.namespace, name), &id, func() error {
-		if c.loadFn == nil {
-			return fmt.Errorf("missing loadFn")
-		}
+	return c.GetOrSetCtx(context.Background(), name)
+}
 
-		// Load ID
-		id, err = c.loadFn(name)
-		if err != nil {
-			return err
+func (c *idCache) GetOrSetCtx(ctx context.Context, name string) (id string, err error) {
+	err = c.cache.GetOrLoadCtx(ctx, cache.MakeKey(c.namespace, name), &id, defaultTTL, func(ctx context.Context) (interface{}, error) {
+		if c.loadFn == nil {
+			return nil, fmt.Errorf("missing loadFn")
 		}
 
-		// Set cache
-		return c.cache.Set(cache.MakeKey(c.namespace, name), id, defaultTTL)
+		// Load ID, concurrent loads of the same name are coalesced
+		return c.group.Load(ctx, name, func(ctx context.Context) (interface{}, error) {
+			return c.loadFn(ctx, name)
+		})
 	})
 
 	return id, err
 }
 
 func (c *idCache) IsExist(name string) (ok bool, err error) {
-	return c.cache.IsExist(cache.MakeKey(c.namespace, name))
+	return c.IsExistCtx(context.Background(), name)
+}
+
+func (c *idCache) IsExistCtx(ctx context.Context, name string) (ok bool, err error) {
+	return c.cache.IsExistCtx(ctx, cache.MakeKey(c.namespace, name))
 }
 
 func (c *idCache) Delete(name string) (err error) {
-	_, err = c.cache.Delete(cache.MakeKey(c.namespace, name))
+	return c.DeleteCtx(context.Background(), name)
+}
+
+func (c *idCache) DeleteCtx(ctx context.Context, name string) (err error) {
+	_, err = c.cache.DeleteCtx(ctx, cache.MakeKey(c.namespace, name))
 	return err
 }
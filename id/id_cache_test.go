@@ -1,7 +1,11 @@
 package id
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hoaitan/cache/local"
 	"github.com/stretchr/testify/assert"
@@ -55,3 +59,68 @@ func TestNew_With_Missing_LoadFn(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "", id)
 }
+
+func TestNew_With_LoadCtxFn(t *testing.T) {
+	name := "abc"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	loadCount := 0
+	cache := New(local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	}), "test-id-cache").SetLoadCtxFn(func(_ctx context.Context, name string) (id string, err error) {
+		loadCount++
+
+		// Load function receives the deadline of the caller's context
+		deadline, _ := ctx.Deadline()
+		_deadline, ok := _ctx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, deadline, _deadline)
+		assert.Nil(t, _ctx.Err())
+
+		return name, nil
+	})
+
+	id, err := cache.GetOrSetCtx(ctx, name)
+	assert.Nil(t, err)
+	assert.Equal(t, name, id)
+	assert.Equal(t, 1, loadCount)
+
+	// Canceled context does not start a load
+	cancel()
+	id, err = cache.GetOrSetCtx(ctx, "def")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", id)
+	assert.Equal(t, 1, loadCount)
+
+	ok, err := cache.IsExistCtx(context.Background(), "def")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestGetOrSet_Coalesce(t *testing.T) {
+	var loadCount int32
+	cache := New(local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	}), "test-id-cache").SetLoadFn(func(name string) (id string, err error) {
+		atomic.AddInt32(&loadCount, 1)
+		time.Sleep(100 * time.Millisecond)
+		return name, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			id, err := cache.GetOrSet("abc")
+			assert.Nil(t, err)
+			assert.Equal(t, "abc", id)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loadCount)
+}
@@ -0,0 +1,95 @@
+// Package coalesce coalesces concurrent cache misses of the same key
+package coalesce
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Group coalesces concurrent calls per key, a nil Group calls fn directly
+type Group struct {
+	missGroup singleflight.Group
+	loadGroup singleflight.Group
+}
+
+// New returns a Group if enable, otherwise nil
+func New(enable bool) *Group {
+	if !enable {
+		return nil
+	}
+
+	return &Group{}
+}
+
+// Miss calls fn once for concurrent misses of key, isShared=true for callers which waited for fn of another caller.
+// Because fn has side effects on its caller, waiting callers should look up the cache again.
+func (g *Group) Miss(key string, fn func() error) (isShared bool, err error) {
+	if g == nil {
+		return false, fn()
+	}
+
+	isShared = true
+	_, err, _ = g.missGroup.Do(key, func() (interface{}, error) {
+		isShared = false
+		return nil, fn()
+	})
+
+	return isShared, err
+}
+
+// Load calls fn once for concurrent loads of key and shares its result, waiting callers return early if their ctx is done.
+// fn runs with a ctx having the values and the deadline of the first caller's ctx but not its cancellation,
+// so a canceled caller does not fail the others. A caller whose ctx is already done does not start a load.
+func (g *Group) Load(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if g == nil {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ch := g.loadGroup.DoChan(key, func() (interface{}, error) {
+		loadCtx := detach(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithDeadline(loadCtx, deadline)
+			defer cancel()
+		}
+
+		return fn(loadCtx)
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// detachedCtx has the values of its parent but neither its deadline nor its cancellation
+type detachedCtx struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedCtx{parent: ctx}
+}
+
+func (detachedCtx) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (detachedCtx) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedCtx) Err() error {
+	return nil
+}
+
+func (c detachedCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,148 @@
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_Load(t *testing.T) {
+	g := New(true)
+	var callCount int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			v, err := g.Load(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&callCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, v)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), callCount)
+}
+
+func TestGroup_Load_Canceled(t *testing.T) {
+	g := New(true)
+	go g.Load(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return 1, nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	// Waiting caller returns early
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.Load(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		return 2, nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestGroup_Load_FirstCallerCanceled(t *testing.T) {
+	type ctxKey struct{}
+	g := New(true)
+
+	// First caller is canceled while loading
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	go g.Load(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return 1, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	// Waiting caller gets the shared result
+	v, err := g.Load(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return 2, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// Canceled caller does not start a load
+	_, err = g.Load(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		assert.Fail(t, "load of canceled caller")
+		return 3, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestGroup_Load_Deadline(t *testing.T) {
+	g := New(true)
+
+	// Deadline of the first caller is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	_, err := g.Load(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		_deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, deadline, _deadline)
+
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestGroup_Miss(t *testing.T) {
+	g := New(true)
+	var callCount, sharedCount int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			isShared, err := g.Miss("key", func() error {
+				atomic.AddInt32(&callCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			})
+			assert.Nil(t, err)
+			if isShared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), callCount)
+	assert.Equal(t, int32(9), sharedCount)
+}
+
+func TestGroup_Disable(t *testing.T) {
+	var g *Group = New(false)
+	assert.Nil(t, g)
+
+	isShared, err := g.Miss("key", func() error {
+		return nil
+	})
+	assert.False(t, isShared)
+	assert.Nil(t, err)
+
+	v, err := g.Load(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+}
@@ -0,0 +1,85 @@
+// Package envelope wraps cached values with timestamps, so a value can become stale before the cache backend expires it
+package envelope
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// magic marks an enveloped value, no gob, JSON, msgpack or protobuf value starts with it
+const magic = "\x00cache"
+
+const headerSize = len(magic) + 8 + 8
+
+type Envelope struct {
+	FreshUntil time.Time     // Value is stale after FreshUntil, zero value is always fresh
+	Delta      time.Duration // Time to recompute the value, used by early expiration
+	Payload    []byte
+}
+
+// Encode envelope e
+func (e Envelope) Encode() []byte {
+	b := make([]byte, headerSize, headerSize+len(e.Payload))
+	copy(b, magic)
+
+	var freshUntil int64
+	if !e.FreshUntil.IsZero() {
+		freshUntil = e.FreshUntil.UnixNano()
+	}
+	binary.BigEndian.PutUint64(b[len(magic):], uint64(freshUntil))
+	binary.BigEndian.PutUint64(b[len(magic)+8:], uint64(e.Delta))
+
+	return append(b, e.Payload...)
+}
+
+// IsStale is true if the value is stale at now.
+// With beta > 0, the value expires early with a probability weighted by Delta (XFetch), rnd returns a number in (0, 1].
+func (e Envelope) IsStale(now time.Time, beta float64, rnd func() float64) bool {
+	if e.FreshUntil.IsZero() {
+		return false
+	}
+	if beta <= 0 || e.Delta <= 0 {
+		return now.After(e.FreshUntil)
+	}
+
+	early := -float64(e.Delta) * beta * math.Log(rnd())
+	return float64(e.FreshUntil.Sub(now)) <= early
+}
+
+// Rand returns a pseudo-random number in (0, 1], it is the default random source of early expiration
+func Rand() float64 {
+	return 1 - rand.Float64()
+}
+
+// Decode enveloped value b, b which is not enveloped becomes an always fresh payload
+func Decode(b []byte) Envelope {
+	if len(b) < headerSize || string(b[:len(magic)]) != magic {
+		return Envelope{Payload: b}
+	}
+
+	e := Envelope{
+		Delta:   time.Duration(binary.BigEndian.Uint64(b[len(magic)+8:])),
+		Payload: b[headerSize:],
+	}
+	if freshUntil := int64(binary.BigEndian.Uint64(b[len(magic):])); freshUntil != 0 {
+		e.FreshUntil = time.Unix(0, freshUntil)
+	}
+
+	return e
+}
+
+type refreshKey struct{}
+
+// RefreshContext marks ctx of a background refresh, so lower cache layers load stale values instead of serving them
+func RefreshContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// IsRefresh is true if ctx is of a background refresh
+func IsRefresh(ctx context.Context) bool {
+	isRefresh, _ := ctx.Value(refreshKey{}).(bool)
+	return isRefresh
+}
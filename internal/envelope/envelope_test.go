@@ -0,0 +1,84 @@
+package envelope
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	now := time.Now()
+	e := Envelope{
+		FreshUntil: now.Add(time.Second),
+		Payload:    []byte("payload"),
+	}
+
+	decoded := Decode(e.Encode())
+	assert.Equal(t, e.Payload, decoded.Payload)
+	assert.True(t, e.FreshUntil.Equal(decoded.FreshUntil))
+	assert.False(t, decoded.IsStale(now, 0, nil))
+	assert.True(t, decoded.IsStale(now.Add(2*time.Second), 0, nil))
+
+	// Always fresh
+	decoded = Decode(Envelope{Payload: []byte("payload")}.Encode())
+	assert.True(t, decoded.FreshUntil.IsZero())
+	assert.False(t, decoded.IsStale(now.Add(time.Hour), 0, nil))
+}
+
+func TestDecode_NotEnveloped(t *testing.T) {
+	for _, v := range []interface{}{0, 1, "", "test", []int{1}} {
+		for _, cd := range []cache.Codec{codec.Gob, codec.JSON, codec.Msgpack} {
+			b, err := cd.Marshal(v)
+			assert.Nil(t, err)
+
+			e := Decode(b)
+			assert.Equal(t, b, e.Payload)
+			assert.False(t, e.IsStale(time.Now(), 0, nil))
+		}
+	}
+}
+
+func TestEnvelope_EarlyExpiration(t *testing.T) {
+	now := time.Now()
+	e := Decode(Envelope{
+		FreshUntil: now.Add(time.Second),
+		Delta:      100 * time.Millisecond,
+		Payload:    []byte("payload"),
+	}.Encode())
+	assert.Equal(t, 100*time.Millisecond, e.Delta)
+
+	// rnd=1: never expires early
+	rnd := func() float64 { return 1 }
+	assert.False(t, e.IsStale(now.Add(999*time.Millisecond), 1, rnd))
+	assert.True(t, e.IsStale(now.Add(time.Second), 1, rnd))
+
+	// rnd=e^-5: expires 500ms early with beta=1
+	rnd = func() float64 { return math.Exp(-5) }
+	assert.False(t, e.IsStale(now.Add(400*time.Millisecond), 1, rnd))
+	assert.True(t, e.IsStale(now.Add(600*time.Millisecond), 1, rnd))
+
+	// Higher beta expires earlier
+	assert.True(t, e.IsStale(now.Add(400*time.Millisecond), 2, rnd))
+
+	// rnd=0: expires now
+	assert.True(t, e.IsStale(now, 1, func() float64 { return 0 }))
+
+	// beta=0: disabled
+	assert.False(t, e.IsStale(now.Add(600*time.Millisecond), 0, rnd))
+}
+
+func TestRecomputes(t *testing.T) {
+	r := &Recomputes{}
+	assert.Equal(t, time.Duration(0), r.Delta("key"))
+
+	done := r.Start("key")
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, r.Delta("key") >= 10*time.Millisecond)
+
+	done()
+	assert.Equal(t, time.Duration(0), r.Delta("key"))
+}
@@ -0,0 +1,30 @@
+package envelope
+
+import (
+	"sync"
+	"time"
+)
+
+// Recomputes tracks keys being recomputed by miss cache functions, so Set knows how long the recompute took
+type Recomputes struct {
+	starts sync.Map
+}
+
+// Start recomputing key, done must be called after the recompute
+func (r *Recomputes) Start(key string) (done func()) {
+	r.starts.Store(key, time.Now())
+
+	return func() {
+		r.starts.Delete(key)
+	}
+}
+
+// Delta returns the elapsed time of the recompute of key, 0 if key is not being recomputed
+func (r *Recomputes) Delta(key string) time.Duration {
+	start, ok := r.starts.Load(key)
+	if !ok {
+		return 0
+	}
+
+	return time.Since(start.(time.Time))
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Jitter spreads expiry of keys set with the same TTL, a random duration up to Max + Percent of TTL is added to TTL
+type Jitter struct {
+	Max     time.Duration  // Max absolute jitter
+	Percent float64        // Max jitter in percent of TTL, e.g. 10 adds up to 10% of TTL
+	Rand    func() float64 // Random source in [0, 1), default: math/rand
+}
+
+// IsEnable is true if the jitter changes TTL
+func (j Jitter) IsEnable() bool {
+	return j.Max > 0 || j.Percent > 0
+}
+
+// Apply jitter to ttl, ttl <= 0 (no expire) is not changed
+func (j Jitter) Apply(ttl time.Duration) time.Duration {
+	if ttl <= 0 || !j.IsEnable() {
+		return ttl
+	}
+
+	rnd := j.Rand
+	if rnd == nil {
+		rnd = rand.Float64
+	}
+
+	max := j.Max + time.Duration(float64(ttl)*j.Percent/100)
+	return ttl + time.Duration(rnd()*float64(max))
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitter_Apply(t *testing.T) {
+	rnd := 0.5
+	tests := []struct {
+		name   string
+		jitter Jitter
+		ttl    time.Duration
+		want   time.Duration
+	}{
+		{
+			"Disabled",
+			Jitter{},
+			10 * time.Second,
+			10 * time.Second,
+		},
+		{
+			"Absolute",
+			Jitter{Max: 2 * time.Second},
+			10 * time.Second,
+			11 * time.Second,
+		},
+		{
+			"Percent",
+			Jitter{Percent: 20},
+			10 * time.Second,
+			11 * time.Second,
+		},
+		{
+			"Absolute and percent",
+			Jitter{Max: 2 * time.Second, Percent: 20},
+			10 * time.Second,
+			12 * time.Second,
+		},
+		{
+			"No expire",
+			Jitter{Max: 2 * time.Second},
+			0,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.jitter.Rand = func() float64 {
+				return rnd
+			}
+			assert.Equal(t, tt.want, tt.jitter.Apply(tt.ttl))
+		})
+	}
+}
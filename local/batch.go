@@ -0,0 +1,155 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+func (c *localCache) GetMany(keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	return c.GetManyCtx(context.Background(), keys, factory)
+}
+
+// GetManyCtx gets cached values of keys one by one, stale values are missing
+func (c *localCache) GetManyCtx(ctx context.Context, keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	found = make(map[string]interface{}, len(keys))
+	if !c.IsEnable() {
+		return found, nil
+	}
+
+	for _, key := range keys {
+		v, err := c.get(key)
+		if err != nil {
+			continue
+		}
+
+		ptr := factory()
+		if err = c.codec.Unmarshal(v, ptr); err != nil {
+			return found, err
+		}
+		found[key] = ptr
+	}
+
+	return found, nil
+}
+
+func (c *localCache) GetManyOrLoad(keys []string, factory cache.PtrFactory, ttl int, loader cache.LoadManyFn) (found map[string]interface{}, err error) {
+	return c.GetManyOrLoadCtx(context.Background(), keys, factory, cache.Seconds(ttl), loader.WithContext())
+}
+
+// GetManyOrLoadCtx gets cached values of keys, missing values are loaded at once by loader and cached with ttl
+func (c *localCache) GetManyOrLoadCtx(ctx context.Context, keys []string, factory cache.PtrFactory, ttl time.Duration, loader cache.LoadManyCtxFn) (found map[string]interface{}, err error) {
+	found, err = c.GetManyCtx(ctx, keys, factory)
+	if err != nil {
+		return found, err
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 || loader == nil {
+		return found, nil
+	}
+
+	// Load missing cache
+	start := time.Now()
+	values, err := loader(ctx, missing)
+	if err != nil {
+		return found, err
+	}
+	delta := time.Since(start)
+
+	for _, key := range missing {
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		// Decode loaded value the same way as cached value
+		b, err := c.codec.Marshal(v)
+		if err != nil {
+			return found, err
+		}
+		ptr := factory()
+		if err = c.codec.Unmarshal(b, ptr); err != nil {
+			return found, err
+		}
+		found[key] = ptr
+
+		if !c.IsEnable() {
+			continue
+		}
+		if err = c.set(key, b, ttl, delta); err != nil {
+			return found, err
+		}
+	}
+
+	return found, nil
+}
+
+func (c *localCache) SetMany(items map[string]interface{}, ttl int) (err error) {
+	return c.SetManyCtx(context.Background(), items, cache.Seconds(ttl))
+}
+
+// SetManyCtx sets items one by one
+func (c *localCache) SetManyCtx(ctx context.Context, items map[string]interface{}, ttl time.Duration) (err error) {
+	for key, data := range items {
+		if err = c.SetCtx(ctx, key, data, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *localCache) DeleteMany(keys []string) (count int, err error) {
+	return c.DeleteManyCtx(context.Background(), keys)
+}
+
+// DeleteManyCtx deletes keys one by one, count is the number of deleted keys
+func (c *localCache) DeleteManyCtx(ctx context.Context, keys []string) (count int, err error) {
+	for _, key := range keys {
+		ok, err := c.DeleteCtx(ctx, key)
+		if err != nil {
+			return count, err
+		}
+		if ok {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (c *localCache) DeleteByPrefix(prefix string) (count int, err error) {
+	return c.DeleteByPrefixCtx(context.Background(), prefix)
+}
+
+// DeleteByPrefixCtx iterates all entries and deletes keys starting with prefix, count is the number of deleted keys
+func (c *localCache) DeleteByPrefixCtx(ctx context.Context, prefix string) (count int, err error) {
+	if !c.IsEnable() {
+		return 0, nil
+	}
+
+	// Collect keys first, deleting entries during the iteration shifts its position and may skip keys
+	var keys [][]byte
+	it := c.cacheEngine.NewIterator()
+	for entry := it.Next(); entry != nil; entry = it.Next() {
+		if bytes.HasPrefix(entry.Key, []byte(prefix)) {
+			keys = append(keys, entry.Key)
+		}
+	}
+
+	for _, key := range keys {
+		if c.cacheEngine.Del(key) {
+			count++
+		}
+	}
+
+	return count, nil
+}
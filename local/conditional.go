@@ -0,0 +1,78 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/envelope"
+)
+
+func (c *localCache) SetNX(key string, data interface{}, ttl int) (ok bool, err error) {
+	return c.SetNXCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+// SetNXCtx checks then sets key under a striped lock, a stale value is missing
+func (c *localCache) SetNXCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (ok bool, err error) {
+	if !c.IsEnable() {
+		return false, nil
+	}
+
+	b, err := c.codec.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	defer c.locks.lock(key)()
+
+	if _, found := c.current(key); found {
+		return false, nil
+	}
+
+	return true, c.set(key, b, ttl, c.recomputes.Delta(key))
+}
+
+func (c *localCache) CompareAndSwap(key string, old, new interface{}, ttl int) (ok bool, err error) {
+	return c.CompareAndSwapCtx(context.Background(), key, old, new, cache.Seconds(ttl))
+}
+
+// CompareAndSwapCtx compares encoded values then sets key under a striped lock
+func (c *localCache) CompareAndSwapCtx(ctx context.Context, key string, old, new interface{}, ttl time.Duration) (ok bool, err error) {
+	if !c.IsEnable() {
+		return false, nil
+	}
+
+	oldB, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newB, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	defer c.locks.lock(key)()
+
+	v, found := c.current(key)
+	if !found || !bytes.Equal(v, oldB) {
+		return false, nil
+	}
+
+	return true, c.set(key, newB, ttl, c.recomputes.Delta(key))
+}
+
+// current returns the value of key unless it is missing or stale, early expiration is ignored for conditional writes
+func (c *localCache) current(key string) (v []byte, found bool) {
+	b, err := c.cacheEngine.Get([]byte(key))
+	if err != nil {
+		return nil, false
+	}
+
+	e := envelope.Decode(b)
+	if e.IsStale(time.Now(), 0, nil) {
+		return nil, false
+	}
+
+	return e.Payload, true
+}
@@ -1,7 +1,29 @@
 package local
 
+import (
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
 type Config struct {
-	Enable     bool
-	Size       int // in KB
-	DefaultTTL int // in seconds
+	Enable            bool
+	Size              int            // in KB
+	DefaultTTL        int            // in seconds, Deprecated: use DefaultExpiration
+	DefaultExpiration time.Duration  // Default TTL, overrides DefaultTTL, freecache expires in seconds
+	TTLJitter         cache.Jitter   // Jitter added to TTL, includes DefaultTTL
+	StaleTTL          time.Duration  // Serve stale value for StaleTTL after TTL by GetOrLoad only, which refreshes it in background. Get and Lookup treat it as a miss
+	EarlyExpiration   float64        // XFetch beta, > 0 expires values early with a probability weighted by their recompute time, 1 is a good default
+	Rand              func() float64 // Random source in (0, 1] of early expiration, default: math/rand
+	Coalesce          bool           // Coalesce concurrent loads of a missing key, only one loader runs per key
+	Codec             cache.Codec    // default: codec.Gob
+}
+
+// defaultTTL is DefaultExpiration, DefaultTTL if it is not set
+func (cf Config) defaultTTL() time.Duration {
+	if cf.DefaultExpiration > 0 {
+		return cf.DefaultExpiration
+	}
+
+	return time.Duration(cf.DefaultTTL) * time.Second
 }
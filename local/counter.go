@@ -0,0 +1,66 @@
+package local
+
+import (
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/envelope"
+)
+
+func (c *localCache) Incr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.IncrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// IncrCtx increments the counter under a striped lock, the counter is encoded by the codec so Get can read it
+func (c *localCache) IncrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	if !c.IsEnable() {
+		return 0, nil
+	}
+
+	defer c.locks.lock(key)()
+
+	// Missing or stale counter is created with ttl
+	b, expireAt, err := c.cacheEngine.GetWithExpiration([]byte(key))
+	e := envelope.Decode(b)
+	if err != nil || e.IsStale(time.Now(), 0, nil) {
+		if b, err = c.codec.Marshal(delta); err != nil {
+			return 0, err
+		}
+
+		return delta, c.set(key, b, ttl, 0)
+	}
+
+	if err = c.codec.Unmarshal(e.Payload, &value); err != nil {
+		return 0, err
+	}
+	value += delta
+
+	if e.Payload, err = c.codec.Marshal(value); err != nil {
+		return 0, err
+	}
+	b = e.Payload
+	if !e.FreshUntil.IsZero() {
+		b = e.Encode()
+	}
+
+	// Expiration of the counter is kept
+	seconds := 0
+	if expireAt > 0 {
+		seconds = int(int64(expireAt) - time.Now().Unix())
+		if seconds <= 0 {
+			seconds = 1
+		}
+	}
+
+	return value, c.cacheEngine.Set([]byte(key), b, seconds)
+}
+
+func (c *localCache) Decr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.DecrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// DecrCtx decrements the counter under a striped lock
+func (c *localCache) DecrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	return c.IncrCtx(ctx, key, -delta, ttl)
+}
@@ -0,0 +1,54 @@
+package local
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/coocood/freecache"
+	"github.com/hoaitan/cache"
+)
+
+func (c *localCache) Keys(prefix string) cache.Iterator {
+	return c.KeysCtx(context.Background(), prefix)
+}
+
+// KeysCtx iterates keys starting with prefix by freecache iterator, expired entries are skipped
+func (c *localCache) KeysCtx(ctx context.Context, prefix string) cache.Iterator {
+	if !c.IsEnable() {
+		return &keyIterator{}
+	}
+
+	return &keyIterator{it: c.cacheEngine.NewIterator(), prefix: []byte(prefix)}
+}
+
+// keyIterator filters keys of freecache iterator by prefix
+type keyIterator struct {
+	it     *freecache.Iterator
+	prefix []byte
+	key    string
+}
+
+func (it *keyIterator) Next() bool {
+	if it.it == nil {
+		return false
+	}
+
+	for entry := it.it.Next(); entry != nil; entry = it.it.Next() {
+		if bytes.HasPrefix(entry.Key, it.prefix) {
+			it.key = string(entry.Key)
+			return true
+		}
+	}
+
+	// End of iteration
+	it.it = nil
+	return false
+}
+
+func (it *keyIterator) Key() string {
+	return it.key
+}
+
+func (it *keyIterator) Err() error {
+	return nil
+}
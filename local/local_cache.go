@@ -1,69 +1,150 @@
 package local
 
 import (
-	"bytes"
-	"encoding/gob"
+	"context"
+	"time"
 
 	"github.com/coocood/freecache"
 	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/codec"
+	"github.com/hoaitan/cache/internal/coalesce"
+	"github.com/hoaitan/cache/internal/envelope"
 )
 
 type localCache struct {
 	cacheEngine *freecache.Cache
 	cf          Config
+	codec       cache.Codec
+	group       *coalesce.Group
+	refresher   *coalesce.Group
+	recomputes  *envelope.Recomputes
+	rand        func() float64
+	locks       stripedLock
 }
 
 // New local cache with size (byte, min = 512KB)
 func New(cf Config) cache.Cache {
-	return &localCache{
+	c := &localCache{
 		cacheEngine: freecache.NewCache(cf.Size),
 		cf:          cf,
+		codec:       cf.Codec,
+		group:       coalesce.New(cf.Coalesce),
+		refresher:   coalesce.New(true),
+		recomputes:  &envelope.Recomputes{},
+		rand:        cf.Rand,
 	}
+	if c.codec == nil {
+		c.codec = codec.Gob
+	}
+	if c.rand == nil {
+		c.rand = envelope.Rand
+	}
+
+	return c
 }
 
 func (c *localCache) Set(key string, data interface{}, ttl int) (err error) {
+	return c.SetCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+func (c *localCache) SetCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (err error) {
 	if !c.IsEnable() {
 		return nil
 	}
-	if ttl < 0 {
-		ttl = c.cf.DefaultTTL
-	}
 
 	// Encode data
-	b, err := encode(data)
+	b, err := c.codec.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	// Set value to cache engine
-	return c.cacheEngine.Set([]byte(key), b, ttl)
+	// Set waits for conditional writes of key
+	defer c.locks.lock(key)()
+
+	return c.set(key, b, ttl, c.recomputes.Delta(key))
 }
 
 func (c *localCache) Get(key string, ptr interface{}, fn cache.MissCacheFn) (err error) {
+	return c.GetCtx(context.Background(), key, ptr, fn.WithContext())
+}
+
+func (c *localCache) GetCtx(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) (err error) {
+	found, err := c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	// Call function if missing cache
+	if fn == nil {
+		return nil
+	}
+
+	return c.miss(ctx, key, ptr, fn)
+}
+
+func (c *localCache) Lookup(key string, ptr interface{}) (found bool, err error) {
+	return c.LookupCtx(context.Background(), key, ptr)
+}
+
+func (c *localCache) LookupCtx(ctx context.Context, key string, ptr interface{}) (found bool, err error) {
 	if !c.IsEnable() {
-		return fn()
+		return false, nil
 	}
 
 	// Get cached value
-	v, err := c.cacheEngine.Get([]byte(key))
+	v, err := c.get(key)
 	if err != nil {
-		// Call function if missing cache
-		if fn == nil {
-			return nil
-		}
-
-		return fn()
+		return false, nil
 	}
 
 	// Decode
-	if err = decode(v, ptr); err != nil {
-		return err
+	if err = c.codec.Unmarshal(v, ptr); err != nil {
+		return true, err
 	}
 
-	return nil
+	return true, nil
+}
+
+func (c *localCache) GetOrLoad(key string, ptr interface{}, ttl int, loader cache.LoadFn) (err error) {
+	return c.GetOrLoadCtx(context.Background(), key, ptr, cache.Seconds(ttl), loader.WithContext())
+}
+
+func (c *localCache) GetOrLoadCtx(ctx context.Context, key string, ptr interface{}, ttl time.Duration, loader cache.LoadCtxFn) (err error) {
+	if c.IsEnable() {
+		if v, isStale, err := c.getStale(key); err == nil {
+			switch {
+			case !isStale:
+				return c.codec.Unmarshal(v, ptr)
+			case !envelope.IsRefresh(ctx):
+				// Stale value is served while it is refreshed in background
+				c.refresh(key, ttl, loader)
+				return c.codec.Unmarshal(v, ptr)
+			}
+
+			// Stale value is loaded while refreshing an upper layer
+		}
+	}
+
+	// Load missing cache
+	v, err := c.group.Load(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.load(ctx, key, ttl, loader)
+	})
+
+	// Decode loaded value the same way as cached value
+	if b, _ := v.([]byte); b != nil {
+		if err := c.codec.Unmarshal(b, ptr); err != nil {
+			return err
+		}
+	}
+
+	return err
 }
 
 func (c *localCache) Delete(key string) (ok bool, err error) {
+	return c.DeleteCtx(context.Background(), key)
+}
+
+func (c *localCache) DeleteCtx(ctx context.Context, key string) (ok bool, err error) {
 	if !c.IsEnable() {
 		return false, nil
 	}
@@ -73,11 +154,82 @@ func (c *localCache) Delete(key string) (ok bool, err error) {
 }
 
 func (c *localCache) IsExist(key string) (ok bool, err error) {
+	return c.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx is false for a stale enveloped value, the same as conditional writes
+func (c *localCache) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
 	if !c.IsEnable() {
 		return false, nil
 	}
 
-	if _, err := c.cacheEngine.Get([]byte(key)); err != nil {
+	_, ok = c.current(key)
+
+	return ok, nil
+}
+
+func (c *localCache) TTL(key string) (ttl time.Duration, err error) {
+	return c.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx returns the remaining fresh time of an enveloped value, remaining TTL of freecache otherwise
+func (c *localCache) TTLCtx(ctx context.Context, key string) (ttl time.Duration, err error) {
+	if !c.IsEnable() {
+		return 0, cache.ErrMiss
+	}
+
+	if c.useEnvelope() {
+		b, err := c.cacheEngine.Get([]byte(key))
+		if err != nil {
+			return 0, cache.ErrMiss
+		}
+
+		if e := envelope.Decode(b); !e.FreshUntil.IsZero() {
+			if ttl = time.Until(e.FreshUntil); ttl <= 0 {
+				return 0, cache.ErrMiss
+			}
+
+			return ttl, nil
+		}
+	}
+
+	seconds, err := c.cacheEngine.TTL([]byte(key))
+	if err != nil {
+		return 0, cache.ErrMiss
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (c *localCache) Touch(key string, ttl int) (ok bool, err error) {
+	return c.TouchCtx(context.Background(), key, cache.Seconds(ttl))
+}
+
+// TouchCtx resets TTL of key by freecache Touch, an enveloped value is set again with new fresh time
+func (c *localCache) TouchCtx(ctx context.Context, key string, ttl time.Duration) (ok bool, err error) {
+	if !c.IsEnable() {
+		return false, nil
+	}
+
+	if c.useEnvelope() {
+		// Value is set again under the lock, so a concurrent write is not overwritten by the old value
+		defer c.locks.lock(key)()
+
+		b, err := c.cacheEngine.Get([]byte(key))
+		if err != nil {
+			return false, nil
+		}
+
+		// Stale value is not touched, it is missing for Get
+		e := envelope.Decode(b)
+		if e.IsStale(time.Now(), 0, nil) {
+			return false, nil
+		}
+
+		return true, c.set(key, e.Payload, ttl, e.Delta)
+	}
+
+	if err := c.cacheEngine.Touch([]byte(key), cache.ToSeconds(c.expiration(ttl))); err != nil {
 		return false, nil
 	}
 
@@ -85,6 +237,10 @@ func (c *localCache) IsExist(key string) (ok bool, err error) {
 }
 
 func (c *localCache) Flush() (count int, err error) {
+	return c.FlushCtx(context.Background())
+}
+
+func (c *localCache) FlushCtx(ctx context.Context) (count int, err error) {
 	if !c.IsEnable() {
 		return 0, nil
 	}
@@ -97,6 +253,10 @@ func (c *localCache) Flush() (count int, err error) {
 }
 
 func (c *localCache) IsReady() bool {
+	return c.IsReadyCtx(context.Background())
+}
+
+func (c *localCache) IsReadyCtx(ctx context.Context) bool {
 	return true
 }
 
@@ -108,18 +268,110 @@ func (c *localCache) Close() error {
 	return nil
 }
 
-func encode(data interface{}) ([]byte, error) {
-	buff := new(bytes.Buffer)
-	enc := gob.NewEncoder(buff)
+// miss calls fn for missing cache, concurrent callers of a coalesced key look up the cache set by fn of the first caller
+func (c *localCache) miss(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) error {
+	// Track recompute time of fn for early expiration
+	if c.cf.EarlyExpiration > 0 {
+		defer c.recomputes.Start(key)()
+	}
+
+	isShared, err := c.group.Miss(key, func() error {
+		return fn(ctx)
+	})
+	if !isShared || err != nil {
+		return err
+	}
 
-	err := enc.Encode(data)
-	return buff.Bytes(), err
+	found, err := c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	return fn(ctx)
 }
 
-func decode(data []byte, ptr interface{}) error {
-	buff := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buff)
+// load and cache missing value, returns the encoded value
+func (c *localCache) load(ctx context.Context, key string, ttl time.Duration, loader cache.LoadCtxFn) ([]byte, error) {
+	// Missing value is not loaded without loader
+	if loader == nil {
+		return nil, cache.ErrMiss
+	}
 
-	err := dec.Decode(ptr)
-	return err
+	start := time.Now()
+	v, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delta := time.Since(start)
+
+	b, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.IsEnable() {
+		return b, nil
+	}
+
+	return b, c.set(key, b, ttl, delta)
+}
+
+// refresh stale value of key in background, only one refresh runs per key
+func (c *localCache) refresh(key string, ttl time.Duration, loader cache.LoadCtxFn) {
+	ctx := envelope.RefreshContext(context.Background())
+	go c.refresher.Load(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.load(ctx, key, ttl, loader)
+	})
+}
+
+func (c *localCache) set(key string, b []byte, ttl time.Duration, delta time.Duration) error {
+	expiration := c.expiration(ttl)
+
+	// Keep stale value for StaleTTL after it expires, keep recompute time for early expiration
+	if c.useEnvelope() && expiration > 0 {
+		b = envelope.Envelope{
+			FreshUntil: time.Now().Add(expiration),
+			Delta:      delta,
+			Payload:    b,
+		}.Encode()
+		expiration += c.cf.StaleTTL
+	}
+
+	// Set value to cache engine, freecache expires in seconds
+	return c.cacheEngine.Set([]byte(key), b, cache.ToSeconds(expiration))
+}
+
+// expiration resolves default TTL of ttl and applies jitter
+func (c *localCache) expiration(ttl time.Duration) time.Duration {
+	if ttl < 0 {
+		ttl = c.cf.defaultTTL()
+	}
+
+	return c.cf.TTLJitter.Apply(ttl)
+}
+
+// useEnvelope is true if values are enveloped to become stale before freecache expires them
+func (c *localCache) useEnvelope() bool {
+	return c.cf.StaleTTL > 0 || c.cf.EarlyExpiration > 0
+}
+
+// get returns cache.ErrMiss if key is not found or stale
+func (c *localCache) get(key string) ([]byte, error) {
+	v, isStale, err := c.getStale(key)
+	if err != nil || isStale {
+		return nil, cache.ErrMiss
+	}
+
+	return v, nil
+}
+
+// getStale returns cache.ErrMiss if key is not found, isStale=true if the value is only kept for StaleTTL
+func (c *localCache) getStale(key string) (v []byte, isStale bool, err error) {
+	b, err := c.cacheEngine.Get([]byte(key))
+	if err != nil {
+		return nil, false, cache.ErrMiss
+	}
+
+	e := envelope.Decode(b)
+	return e.Payload, e.IsStale(time.Now(), c.cf.EarlyExpiration, c.rand), nil
 }
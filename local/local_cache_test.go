@@ -2,10 +2,16 @@ package local
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/codec"
 	"github.com/hoaitan/cache/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,150 +71,229 @@ func TestCacheImplement_Disable(t *testing.T) {
 
 }
 
-func TestEncodeAndDecode(t *testing.T) {
-	var (
-		// Bool
-		boolData, boolData2 bool = true, false
+func TestCacheImplement_Codec(t *testing.T) {
+	for _, cd := range []cache.Codec{codec.JSON, codec.Msgpack} {
+		c := New(Config{
+			Enable: true,
+			Size:   1000000,
+			Codec:  cd,
+		})
 
-		// String
-		stringData, stringData2 string = "test", ""
+		for _, fn := range test.GetTestSuite(true) {
+			t.Run(fmt.Sprintf("codec=%T/fn=%s", cd, runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()), func(t *testing.T) {
+				fn(t, c)
+			})
+		}
+	}
+}
 
-		// Int
-		intData, intData2     int   = 1, 0
-		int8Data, int8Data2   int8  = 1, 0
-		int16Data, int16Data2 int32 = 1, 0
-		int32Data, int32Data2 int32 = 1, 0
-		int64Data, int64Data2 int64 = 1, 0
+func TestCoalesce(t *testing.T) {
+	c := New(Config{
+		Enable:   true,
+		Size:     1000000,
+		Coalesce: true,
+	})
+	var loadCount, missCount int32
 
-		uintData, uintData2     uint   = 1, 0
-		uint8Data, uint8Data2   uint8  = 1, 0
-		uint16Data, uint16Data2 uint32 = 1, 0
-		uint32Data, uint32Data2 uint32 = 1, 0
-		uint64Data, uint64Data2 uint64 = 1, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
 
-		byteData, byteData2 byte = 1, 0
-		runeData, runeData2 rune = 1, 0
+			cacheInt := 0
+			err := c.GetOrLoad("test:coalesce:load", &cacheInt, 0, func() (interface{}, error) {
+				atomic.AddInt32(&loadCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, cacheInt)
+		}()
+		go func() {
+			defer wg.Done()
 
-		// Float
-		float32Data, float32Data2 float32 = 1, 0
-		float64Data, float64Data2 float64 = 1, 0
+			cacheInt := 0
+			err := c.Get("test:coalesce:miss", &cacheInt, func() error {
+				atomic.AddInt32(&missCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				cacheInt = 1
+				return c.Set("test:coalesce:miss", cacheInt, 0)
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, cacheInt)
+		}()
+	}
+	wg.Wait()
 
-		// Complex
-		complex64Data, complex64Data2   complex64  = 1, 0
-		complex128Data, complex128Data2 complex128 = 1, 0
+	assert.Equal(t, int32(1), loadCount)
+	assert.Equal(t, int32(1), missCount)
+}
 
-		// Slice
-		sliceData, sliceData2 []int = []int{1}, []int{0}
+func TestTTL_StaleWhileRevalidate(t *testing.T) {
+	c := New(Config{
+		Enable:   true,
+		Size:     1000000,
+		StaleTTL: 10 * time.Second,
+	})
 
-		// Map
-		mapData, mapData2 map[string]int = map[string]int{"test": 1}, map[string]int{"test": 0}
+	// TTL is the remaining fresh time, not the freecache expiration
+	err := c.Set("test:ttl", 1, 1)
+	assert.Nil(t, err)
 
-		// Struct & Pointer
-	)
-	tests := []struct {
-		data              interface{}
-		ptrToSameDataType interface{}
-	}{
-		{
-			boolData,
-			&boolData2,
-		},
-		{
-			stringData,
-			&stringData2,
-		},
-		// Int
-		{
-			intData,
-			&intData2,
-		},
-		{
-			int8Data,
-			&int8Data2,
-		},
-		{
-			int16Data,
-			&int16Data2,
-		},
-		{
-			int32Data,
-			&int32Data2,
-		},
-		{
-			int64Data,
-			&int64Data2,
-		},
-		{
-			uintData,
-			&uintData2,
-		},
-		{
-			uint8Data,
-			&uint8Data2,
-		},
-		{
-			uint16Data,
-			&uint16Data2,
-		},
-		{
-			uint32Data,
-			&uint32Data2,
-		},
-		{
-			uint64Data,
-			&uint64Data2,
-		},
-		{
-			byteData,
-			&byteData2,
-		},
-		{
-			runeData,
-			&runeData2,
-		},
+	ttl, err := c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Second, "ttl: %s", ttl)
 
-		// Float
-		{
-			float32Data,
-			&float32Data2,
-		},
-		{
-			float64Data,
-			&float64Data2,
-		},
+	ok, err := c.IsExist("test:ttl")
+	assert.True(t, ok)
+	assert.Nil(t, err)
 
-		// Complex
-		{
-			complex64Data,
-			&complex64Data2,
-		},
-		{
-			complex128Data,
-			&complex128Data2,
-		},
+	// Touch extends fresh time
+	ok, err = c.Touch("test:ttl", 20)
+	assert.True(t, ok)
+	assert.Nil(t, err)
 
-		// Slice
-		{
-			sliceData,
-			&sliceData2,
-		},
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 19*time.Second && ttl <= 20*time.Second, "ttl: %s", ttl)
 
-		// Map
-		{
-			mapData,
-			&mapData2,
+	// Stale value is missing
+	err = c.Set("test:ttl", 1, 1)
+	assert.Nil(t, err)
+	time.Sleep(1100 * time.Millisecond)
+
+	_, err = c.TTL("test:ttl")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	ok, err = c.IsExist("test:ttl")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	ok, err = c.Touch("test:ttl", 20)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	c := New(Config{
+		Enable:   true,
+		Size:     1000000,
+		StaleTTL: 10 * time.Second,
+	})
+	var loadCount int32
+	loader := func() (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return int(atomic.AddInt32(&loadCount, 1)), nil
+	}
+
+	cacheInt := 0
+	err := c.GetOrLoad("test:stale", &cacheInt, 1, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Stale value is a cache miss for Lookup
+	time.Sleep(1100 * time.Millisecond)
+	found, err := c.Lookup("test:stale", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+
+	// Stale value is served without waiting for a single refresh
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		err = c.GetOrLoad("test:stale", &cacheInt, 1, loader)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, cacheInt)
+		assert.True(t, time.Since(start) < 50*time.Millisecond)
+	}
+
+	// Refreshed value
+	assert.Eventually(t, func() bool {
+		found, err := c.Lookup("test:stale", &cacheInt)
+		return found && err == nil && cacheInt == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loadCount))
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	var rnd atomic.Value
+	rnd.Store(1.0)
+	c := New(Config{
+		Enable:          true,
+		Size:            1000000,
+		EarlyExpiration: 1,
+		Rand: func() float64 {
+			return rnd.Load().(float64)
 		},
+	})
+
+	// Recompute time of loader is kept
+	cacheInt := 0
+	err := c.GetOrLoad("test:early:load", &cacheInt, 10, func() (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	})
+	assert.Nil(t, err)
+
+	// Recompute time of miss cache function is kept
+	err = c.Get("test:early:miss", &cacheInt, func() error {
+		time.Sleep(20 * time.Millisecond)
+		return c.Set("test:early:miss", 1, 10)
+	})
+	assert.Nil(t, err)
+
+	// No recompute time
+	err = c.Set("test:early:set", 1, 10)
+	assert.Nil(t, err)
+
+	// rnd=1: never expires early
+	for _, key := range []string{"test:early:load", "test:early:miss", "test:early:set"} {
+		found, err := c.Lookup(key, &cacheInt)
+		assert.True(t, found, key)
+		assert.Nil(t, err)
 	}
-	for _, tt := range tests {
-		t.Run(fmt.Sprintf("With type=%T", tt.data), func(t *testing.T) {
-			// Set
-			b, err := encode(tt.data)
-			assert.Nil(t, err)
 
-			// Get
-			err = decode(b, tt.ptrToSameDataType)
-			assert.Nil(t, err)
-			assert.Equal(t, tt.data, reflect.Indirect(reflect.ValueOf(tt.ptrToSameDataType)).Interface())
+	// rnd=e^-1000: 20ms recompute time expires 20s early
+	rnd.Store(math.Exp(-1000))
+	for _, key := range []string{"test:early:load", "test:early:miss"} {
+		found, err := c.Lookup(key, &cacheInt)
+		assert.False(t, found, key)
+		assert.Nil(t, err)
+	}
+
+	found, err := c.Lookup("test:early:set", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+}
+
+func TestCacheImplement_Jitter(t *testing.T) {
+	jitter := test.NewJitter(3 * time.Second)
+	c := New(Config{
+		Enable:    true,
+		Size:      1000000,
+		TTLJitter: jitter.Config(),
+	})
+
+	for _, fn := range test.GetTestSuite(true, jitter) {
+		t.Run(fmt.Sprintf("fn=%s", runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()), func(t *testing.T) {
+			fn(t, c)
 		})
 	}
 }
+
+func TestDefaultExpiration(t *testing.T) {
+	c := New(Config{
+		Enable:            true,
+		Size:              1000000,
+		DefaultTTL:        60,
+		DefaultExpiration: 1500 * time.Millisecond,
+	})
+
+	// DefaultExpiration overrides DefaultTTL, freecache rounds it up to seconds
+	err := c.Set("test:default-expiration", 1, -1)
+	assert.Nil(t, err)
+
+	ttl, err := c.TTL("test:default-expiration")
+	assert.Nil(t, err)
+	assert.True(t, ttl > time.Second && ttl <= 2*time.Second, "ttl: %s", ttl)
+}
@@ -0,0 +1,22 @@
+package local
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const lockStripes = 256
+
+// stripedLock locks keys by stripes, only keys of the same stripe wait for each other
+type stripedLock [lockStripes]sync.Mutex
+
+// lock key, the returned unlock must be called
+func (l *stripedLock) lock(key string) (unlock func()) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	m := &l[h.Sum32()%lockStripes]
+	m.Lock()
+
+	return m.Unlock
+}
@@ -0,0 +1,110 @@
+package multi
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+// backfill writes values found in the n-th implement back to the upper implements
+func (c *multiCaches) backfill(ctx context.Context, n int, found map[string]interface{}) {
+	upper := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		upper = append(upper, i)
+	}
+
+	c.backfillTo(ctx, upper, n, found)
+}
+
+// backfillTo writes values found in the n-th implement back to the upper implements
+func (c *multiCaches) backfillTo(ctx context.Context, upper []int, n int, found map[string]interface{}) {
+	if len(upper) == 0 || len(found) == 0 {
+		return
+	}
+
+	if !c.cf.AsyncBackfill {
+		c.setUpper(ctx, upper, n, found)
+		return
+	}
+
+	// ptrs belong to the caller after returning, values are read again from the n-th implement into new ptrs
+	keys := make(map[reflect.Type][]string)
+	for key, ptr := range found {
+		typ := reflect.TypeOf(ptr).Elem()
+		keys[typ] = append(keys[typ], key)
+	}
+	go func() {
+		ctx := context.Background()
+		for typ, keys := range keys {
+			values, err := c.caches[n].GetManyCtx(ctx, keys, func() interface{} {
+				return reflect.New(typ).Interface()
+			})
+
+			// Backfill is best effort, values are already served from the lower implement
+			if err == nil {
+				c.setUpper(ctx, upper, n, values)
+			}
+		}
+	}()
+}
+
+// setUpper sets values found in the n-th implement to the enable upper implements
+func (c *multiCaches) setUpper(ctx context.Context, upper []int, n int, values map[string]interface{}) {
+	if c.cf.BackfillRemainingTTL {
+		c.setUpperRemainingTTL(ctx, upper, n, values)
+		return
+	}
+
+	for _, i := range upper {
+		if !c.caches[i].IsEnable() {
+			continue
+		}
+
+		// Backfill is best effort, values are already served from the lower implement
+		_ = c.caches[i].SetManyCtx(ctx, values, c.backfillTTL(i))
+	}
+}
+
+// setUpperRemainingTTL is setUpper, each value is set with its remaining TTL in the n-th implement
+func (c *multiCaches) setUpperRemainingTTL(ctx context.Context, upper []int, n int, values map[string]interface{}) {
+	for key, v := range values {
+		ttl, err := c.caches[n].TTLCtx(ctx, key)
+
+		for _, i := range upper {
+			if !c.caches[i].IsEnable() {
+				continue
+			}
+
+			// BackfillTTL is used if remaining TTL is unknown
+			_ttl := c.backfillTTL(i)
+			if err == nil {
+				_ttl = ttl
+			}
+
+			// Backfill is best effort, values are already served from the lower implement
+			_ = c.caches[i].SetCtx(ctx, key, v, _ttl)
+		}
+	}
+}
+
+// backfillTTL of the i-th implement
+func (c *multiCaches) backfillTTL(i int) time.Duration {
+	if i < len(c.cf.BackfillTTL) {
+		return c.cf.BackfillTTL[i]
+	}
+
+	return cache.DefaultTTL
+}
+
+// backfilled is returned by the loader of an implement when the value is found in the cache of the n-th implement,
+// so the implement does not cache it with the load TTL, it is backfilled instead
+type backfilled struct {
+	n   int
+	ptr interface{}
+}
+
+func (e *backfilled) Error() string {
+	return "backfilled"
+}
@@ -0,0 +1,212 @@
+package multi
+
+import (
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+// GetMany gets found caches layer by layer, found caches of lower implements are backfilled to upper implements
+func (c *multiCaches) GetMany(keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	return c.GetManyCtx(context.Background(), keys, factory)
+}
+
+// GetManyCtx gets found caches layer by layer, found caches of lower implements are backfilled to upper implements
+func (c *multiCaches) GetManyCtx(ctx context.Context, keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	found = make(map[string]interface{}, len(keys))
+	missing := keys
+	for i, _cache := range c.caches {
+		if len(missing) == 0 {
+			break
+		}
+
+		_found, err := _cache.GetManyCtx(ctx, missing, factory)
+		if err != nil {
+			// Serve from next cache implement if backend is failed
+			if c.cf.OnError != cache.FailOnError && cache.IsBackendError(err) {
+				continue
+			}
+
+			return found, err
+		}
+		if len(_found) == 0 {
+			continue
+		}
+		c.backfill(ctx, i, _found)
+
+		// Only missing keys are asked from the next implement
+		remaining := make([]string, 0, len(missing)-len(_found))
+		for _, key := range missing {
+			if v, ok := _found[key]; ok {
+				found[key] = v
+			} else {
+				remaining = append(remaining, key)
+			}
+		}
+		missing = remaining
+	}
+
+	return found, nil
+}
+
+// GetManyOrLoad gets found caches layer by layer, final missing caches are loaded at once and backfilled to all implements
+func (c *multiCaches) GetManyOrLoad(keys []string, factory cache.PtrFactory, ttl int, loader cache.LoadManyFn) (found map[string]interface{}, err error) {
+	return c.GetManyOrLoadCtx(context.Background(), keys, factory, cache.Seconds(ttl), loader.WithContext())
+}
+
+// GetManyOrLoadCtx gets found caches layer by layer, final missing caches are loaded at once and backfilled to all implements
+func (c *multiCaches) GetManyOrLoadCtx(ctx context.Context, keys []string, factory cache.PtrFactory, ttl time.Duration, loader cache.LoadManyCtxFn) (found map[string]interface{}, err error) {
+	// Same jittered TTL is propagated to all implements
+	ttl = c.cf.TTLJitter.Apply(ttl)
+
+	found, _, err = c.getManyOrLoad(ctx, c.enabledLayers(), keys, factory, ttl, loader)
+
+	return found, err
+}
+
+// getManyOrLoad gets caches from the 1st of layers, only its missing caches are got from the next layers.
+// from is the implement having the cache of found keys which are not loaded by loader.
+// Caches found in a lower implement are backfilled to the upper implements like GetMany instead of being cached with ttl.
+func (c *multiCaches) getManyOrLoad(ctx context.Context, layers []int, keys []string, factory cache.PtrFactory, ttl time.Duration, loader cache.LoadManyCtxFn) (found map[string]interface{}, from map[string]int, err error) {
+	from = make(map[string]int)
+	if len(layers) == 0 {
+		found = make(map[string]interface{}, len(keys))
+		if loader == nil {
+			return found, from, nil
+		}
+
+		values, err := loader(ctx, keys)
+		if err != nil {
+			return found, from, err
+		}
+		for _, key := range keys {
+			v, ok := values[key]
+			if !ok {
+				continue
+			}
+
+			ptr := factory()
+			if err = assign(ptr, v); err != nil {
+				return found, from, err
+			}
+			found[key] = ptr
+		}
+
+		return found, from, nil
+	}
+
+	isLoaded := false
+	var loadErr error
+	var loaded, backfilled map[string]interface{}
+	found, err = c.caches[layers[0]].GetManyOrLoadCtx(ctx, keys, factory, ttl, func(ctx context.Context, missing []string) (map[string]interface{}, error) {
+		isLoaded = true
+		var _from map[string]int
+		loaded, _from, loadErr = c.getManyOrLoad(ctx, layers[1:], missing, factory, ttl, loader)
+		if loadErr != nil || len(_from) == 0 {
+			return loaded, loadErr
+		}
+
+		// Caches of lower implements are backfilled by implement instead of being cached with ttl
+		backfilled = make(map[string]interface{}, len(_from))
+		byLayer := make(map[int]map[string]interface{})
+		for key, n := range _from {
+			if byLayer[n] == nil {
+				byLayer[n] = make(map[string]interface{})
+			}
+			byLayer[n][key] = loaded[key]
+			backfilled[key] = loaded[key]
+			from[key] = n
+			delete(loaded, key)
+		}
+		for n, values := range byLayer {
+			c.backfillTo(ctx, layers[:1], n, values)
+		}
+
+		return loaded, nil
+	})
+	if found != nil {
+		for key, ptr := range backfilled {
+			found[key] = ptr
+		}
+	}
+
+	// Keys not loaded by the loader are cached in the 1st implement
+	for key := range found {
+		_, inFrom := from[key]
+		_, inLoaded := loaded[key]
+		if !inFrom && !inLoaded {
+			from[key] = layers[0]
+		}
+	}
+
+	// Serve from next cache implement if backend is failed
+	if err != nil && c.cf.OnError != cache.FailOnError && cache.IsBackendError(err) {
+		// found is already filled, only backfill is failed
+		if isLoaded && loadErr == nil {
+			return found, from, nil
+		}
+		if !isLoaded {
+			return c.getManyOrLoad(ctx, layers[1:], keys, factory, ttl, loader)
+		}
+	}
+
+	return found, from, err
+}
+
+// SetMany caches items for all implements
+func (c *multiCaches) SetMany(items map[string]interface{}, ttl int) (err error) {
+	return c.SetManyCtx(context.Background(), items, cache.Seconds(ttl))
+}
+
+// SetManyCtx caches items for all implements
+func (c *multiCaches) SetManyCtx(ctx context.Context, items map[string]interface{}, ttl time.Duration) (err error) {
+	// Same jittered TTL is propagated to all implements
+	ttl = c.cf.TTLJitter.Apply(ttl)
+
+	return c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		return _cache.SetManyCtx(ctx, items, ttl)
+	})
+}
+
+// DeleteMany caches in all implements, count is the max number of deleted keys of an implement
+func (c *multiCaches) DeleteMany(keys []string) (count int, err error) {
+	return c.DeleteManyCtx(context.Background(), keys)
+}
+
+// DeleteManyCtx caches in all implements, count is the max number of deleted keys of an implement
+func (c *multiCaches) DeleteManyCtx(ctx context.Context, keys []string) (count int, err error) {
+	err = c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		_count, err := _cache.DeleteManyCtx(ctx, keys)
+		if _count > count {
+			count = _count
+		}
+
+		return err
+	})
+
+	return count, err
+}
+
+// DeleteByPrefix caches in all implements, see DeleteByPrefixCtx
+func (c *multiCaches) DeleteByPrefix(prefix string) (count int, err error) {
+	return c.DeleteByPrefixCtx(context.Background(), prefix)
+}
+
+// DeleteByPrefixCtx caches in all implements which support it, count is the max number of deleted keys of an implement
+func (c *multiCaches) DeleteByPrefixCtx(ctx context.Context, prefix string) (count int, err error) {
+	err = c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		_count, err := _cache.DeleteByPrefixCtx(ctx, prefix)
+		if _count > count {
+			count = _count
+		}
+
+		if err == cache.NotSupportedErr {
+			return nil
+		}
+
+		return err
+	})
+
+	return count, err
+}
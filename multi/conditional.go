@@ -0,0 +1,66 @@
+package multi
+
+import (
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+// SetNX in the last enable implement, see SetNXCtx
+func (c *multiCaches) SetNX(key string, data interface{}, ttl int) (ok bool, err error) {
+	return c.SetNXCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+// SetNXCtx sets key in the last enable implement which is authoritative if it is missing there,
+// the key is deleted from the upper implements
+func (c *multiCaches) SetNXCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (ok bool, err error) {
+	caches := c.enabled()
+	if len(caches) == 0 {
+		return false, nil
+	}
+
+	last := len(caches) - 1
+	if ok, err = caches[last].SetNXCtx(ctx, key, data, ttl); err != nil {
+		return false, err
+	}
+
+	return ok, c.invalidate(ctx, key)
+}
+
+// CompareAndSwap in the last enable implement, see CompareAndSwapCtx
+func (c *multiCaches) CompareAndSwap(key string, old, new interface{}, ttl int) (ok bool, err error) {
+	return c.CompareAndSwapCtx(context.Background(), key, old, new, cache.Seconds(ttl))
+}
+
+// CompareAndSwapCtx swaps key in the last enable implement which is authoritative,
+// the key is deleted from the upper implements even if it is not swapped, so the next Get reads the authoritative value
+func (c *multiCaches) CompareAndSwapCtx(ctx context.Context, key string, old, new interface{}, ttl time.Duration) (ok bool, err error) {
+	caches := c.enabled()
+	if len(caches) == 0 {
+		return false, nil
+	}
+
+	last := len(caches) - 1
+	if ok, err = caches[last].CompareAndSwapCtx(ctx, key, old, new, ttl); err != nil {
+		return false, err
+	}
+
+	return ok, c.invalidate(ctx, key)
+}
+
+// invalidate key in the enable implements above the last one after it is written in the authoritative implement
+func (c *multiCaches) invalidate(ctx context.Context, key string) error {
+	last := len(c.caches) - 1
+	for last >= 0 && !c.caches[last].IsEnable() {
+		last--
+	}
+	if last < 0 {
+		return nil
+	}
+
+	return c.each(last, func(_cache cache.ContextCache) error {
+		_, err := _cache.DeleteCtx(ctx, key)
+		return err
+	})
+}
@@ -0,0 +1,19 @@
+package multi
+
+import (
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+type Config struct {
+	TTLJitter            cache.Jitter          // Jitter added to explicit TTL, the same jittered TTL is propagated to all implements
+	Coalesce             bool                  // Coalesce concurrent loads of a missing key, only one loader runs per key
+	OnError              cache.ErrorPolicy     // Get behavior on layer backend errors, default: cache.FailOnError
+	BackfillTTL          []time.Duration       // TTL of values found in a lower implement and written back to upper implements by Get, Lookup, GetOrLoad and their batch variants, per implement, default: cache.DefaultTTL
+	AsyncBackfill        bool                  // Write back values found in a lower implement in background, they are read again from the lower implement
+	BackfillRemainingTTL bool                  // Write back values with their remaining TTL in the lower implement instead of BackfillTTL
+	WritePolicy          Policy                // Whether implement errors of Set, Delete, Touch, Flush... are fatal, default: Strict
+	ReadyPolicy          Policy                // Whether not ready implements make IsReady false, default: Strict (all implements are ready)
+	OnLayerError         func(err *LayerError) // Called with implement errors tolerated by WritePolicy
+}
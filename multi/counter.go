@@ -0,0 +1,40 @@
+package multi
+
+import (
+	"context"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
+// Incr the counter in the last enable implement, see IncrCtx
+func (c *multiCaches) Incr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.IncrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// IncrCtx increments the counter in the last enable implement which is authoritative,
+// the counter is deleted from the upper implements, so Get reads it from the authoritative implement
+func (c *multiCaches) IncrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	caches := c.enabled()
+	if len(caches) == 0 {
+		return 0, nil
+	}
+
+	last := len(caches) - 1
+	if value, err = caches[last].IncrCtx(ctx, key, delta, ttl); err != nil {
+		return 0, err
+	}
+
+	// Counter is already incremented, value is returned with invalidation error
+	return value, c.invalidate(ctx, key)
+}
+
+// Decr the counter in the last enable implement, see IncrCtx
+func (c *multiCaches) Decr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.DecrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// DecrCtx decrements the counter in the last enable implement, see IncrCtx
+func (c *multiCaches) DecrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	return c.IncrCtx(ctx, key, -delta, ttl)
+}
@@ -0,0 +1,68 @@
+package multi
+
+import (
+	"context"
+
+	"github.com/hoaitan/cache"
+)
+
+// Keys of all implements, see KeysCtx
+func (c *multiCaches) Keys(prefix string) cache.Iterator {
+	return c.KeysCtx(context.Background(), prefix)
+}
+
+// KeysCtx iterates keys starting with prefix layer by layer, a key found in several implements is returned once
+func (c *multiCaches) KeysCtx(ctx context.Context, prefix string) cache.Iterator {
+	return &keyIterator{
+		ctx:    ctx,
+		caches: c.enabled(),
+		prefix: prefix,
+		seen:   map[string]struct{}{},
+	}
+}
+
+// keyIterator chains iterators of implements
+type keyIterator struct {
+	ctx    context.Context
+	caches []cache.ContextCache
+	prefix string
+	it     cache.Iterator
+	seen   map[string]struct{}
+	err    error
+}
+
+func (it *keyIterator) Next() bool {
+	for it.err == nil {
+		if it.it == nil {
+			if len(it.caches) == 0 {
+				return false
+			}
+			it.it, it.caches = it.caches[0].KeysCtx(it.ctx, it.prefix), it.caches[1:]
+		}
+
+		if !it.it.Next() {
+			it.err = it.it.Err()
+			it.it = nil
+			continue
+		}
+
+		if _, ok := it.seen[it.it.Key()]; !ok {
+			it.seen[it.it.Key()] = struct{}{}
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *keyIterator) Key() string {
+	if it.it == nil {
+		return ""
+	}
+
+	return it.it.Key()
+}
+
+func (it *keyIterator) Err() error {
+	return it.err
+}
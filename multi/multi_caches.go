@@ -1,78 +1,287 @@
 package multi
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"reflect"
+	"time"
 
 	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/coalesce"
+	"github.com/hoaitan/cache/internal/envelope"
 )
 
 type multiCaches struct {
-	caches []cache.Cache
+	caches []cache.ContextCache
+	cf     Config
+	group  *coalesce.Group
 }
 
 // Multi caches support cache in multi cache implements, order is important
 func New(caches ...cache.Cache) cache.Cache {
-	return &multiCaches{
-		caches: caches,
+	return NewWithConfig(Config{}, caches...)
+}
+
+// NewWithConfig is New with config
+func NewWithConfig(cf Config, caches ...cache.Cache) cache.Cache {
+	c := &multiCaches{
+		caches: make([]cache.ContextCache, 0, len(caches)),
+		cf:     cf,
+		group:  coalesce.New(cf.Coalesce),
 	}
+	for _, _cache := range caches {
+		c.caches = append(c.caches, cache.WithContext(_cache))
+	}
+
+	return c
 }
 
 // Set caches for all implements
 func (c *multiCaches) Set(key string, data interface{}, ttl int) (err error) {
-	for _, cache := range c.caches {
-		if err = cache.Set(key, data, ttl); err != nil {
-			return err
+	return c.SetCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+// SetCtx caches for all implements
+func (c *multiCaches) SetCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (err error) {
+	// Same jittered TTL is propagated to all implements
+	ttl = c.cf.TTLJitter.Apply(ttl)
+
+	return c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		return _cache.SetCtx(ctx, key, data, ttl)
+	})
+}
+
+// Get first found cache in all implements, it is backfilled to upper implements
+func (c *multiCaches) Get(key string, ptr interface{}, fn cache.MissCacheFn) (err error) {
+	return c.GetCtx(context.Background(), key, ptr, fn.WithContext())
+}
+
+// GetCtx first found cache in all implements, it is backfilled to upper implements
+func (c *multiCaches) GetCtx(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) (err error) {
+	found, err := c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	// Call missing fn
+	if fn == nil {
+		return nil
+	}
+
+	// Concurrent callers of a coalesced key look up the cache set by fn of the first caller
+	isShared, err := c.group.Miss(key, func() error {
+		return fn(ctx)
+	})
+	if !isShared || err != nil {
+		return err
+	}
+
+	found, err = c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	return fn(ctx)
+}
+
+// Lookup first found cache in all implements, it is backfilled to upper implements
+func (c *multiCaches) Lookup(key string, ptr interface{}) (found bool, err error) {
+	return c.LookupCtx(context.Background(), key, ptr)
+}
+
+// LookupCtx first found cache in all implements, it is backfilled to upper implements
+func (c *multiCaches) LookupCtx(ctx context.Context, key string, ptr interface{}) (found bool, err error) {
+	for i, _cache := range c.caches {
+		found, err = _cache.LookupCtx(ctx, key, ptr)
+		if err == nil {
+			if found {
+				c.backfill(ctx, i, map[string]interface{}{key: ptr})
+				return true, nil
+			}
+
+			// Try next cache implement if missing cache
+			continue
+		}
+
+		// Serve from next cache implement if backend is failed
+		if c.cf.OnError != cache.FailOnError && cache.IsBackendError(err) {
+			continue
 		}
+
+		return found, err
 	}
 
-	return nil
+	return false, nil
 }
 
-// Get first found cache in all implements
-func (c *multiCaches) Get(key string, ptr interface{}, fn cache.MissCacheFn) (err error) {
-	for _, cache := range c.caches {
-		isFound := true
-		checkFn := func() error {
-			isFound = false
-			return nil
+// GetOrLoad first found cache in all implements, missing cache is loaded and backfilled to upper implements
+func (c *multiCaches) GetOrLoad(key string, ptr interface{}, ttl int, loader cache.LoadFn) (err error) {
+	return c.GetOrLoadCtx(context.Background(), key, ptr, cache.Seconds(ttl), loader.WithContext())
+}
+
+// GetOrLoadCtx first found cache in all implements, missing cache is loaded and backfilled to upper implements
+func (c *multiCaches) GetOrLoadCtx(ctx context.Context, key string, ptr interface{}, ttl time.Duration, loader cache.LoadCtxFn) (err error) {
+	// Same jittered TTL is propagated to all implements
+	ttl = c.cf.TTLJitter.Apply(ttl)
+
+	// Missing value is not loaded without loader
+	if loader == nil {
+		loader = func(context.Context) (interface{}, error) {
+			return nil, cache.ErrMiss
 		}
+	}
+
+	// Only one loader runs for concurrent loads of a coalesced key
+	_, err = c.getOrLoad(ctx, c.enabledLayers(), key, ptr, ttl, func(ctx context.Context) (interface{}, error) {
+		return c.group.Load(ctx, key, func(ctx context.Context) (interface{}, error) {
+			return loader(ctx)
+		})
+	})
+
+	return err
+}
 
-		if err = cache.Get(key, ptr, checkFn); err != nil {
-			return err
+// getOrLoad gets cache from the 1st of layers, its missing cache is loaded from the next layers.
+// found is the implement having the cache, -1 if it is loaded by loader.
+// A cache found in a lower implement is backfilled to the upper implements like Get instead of being cached with ttl.
+func (c *multiCaches) getOrLoad(ctx context.Context, layers []int, key string, ptr interface{}, ttl time.Duration, loader cache.LoadCtxFn) (found int, err error) {
+	if len(layers) == 0 {
+		v, err := loader(ctx)
+		if err != nil {
+			return -1, err
 		}
 
-		if isFound {
-			return nil
+		return -1, assign(ptr, v)
+	}
+
+	isRefresh := envelope.IsRefresh(ctx)
+	isLoaded := false
+	var loadErr error
+	err = c.caches[layers[0]].GetOrLoadCtx(ctx, key, ptr, ttl, func(_ctx context.Context) (interface{}, error) {
+		v, err := c.load(_ctx, layers, key, ptr, ttl, loader)
+
+		// Stale value is refreshed in background after GetOrLoadCtx returns
+		if isRefresh || !envelope.IsRefresh(_ctx) {
+			isLoaded, loadErr = true, err
 		}
+
+		return v, err
+	})
+
+	// Cache is found in a lower implement
+	var b *backfilled
+	if errors.As(err, &b) {
+		return b.n, assign(ptr, b.ptr)
 	}
 
-	// Call missing fn
-	if fn == nil {
+	// Serve from next cache implement if backend is failed
+	if err != nil && c.cf.OnError != cache.FailOnError && cache.IsBackendError(err) {
+		// ptr is already filled, only backfill is failed
+		if isLoaded && loadErr == nil {
+			return -1, nil
+		}
+		if !isLoaded {
+			return c.getOrLoad(ctx, layers[1:], key, ptr, ttl, loader)
+		}
+	}
+	if err != nil || isLoaded {
+		return -1, err
+	}
+
+	return layers[0], nil
+}
+
+// load is the loader of the 1st of layers, a cache found in a lower implement is backfilled and returned as backfilled error
+func (c *multiCaches) load(ctx context.Context, layers []int, key string, ptr interface{}, ttl time.Duration, loader cache.LoadCtxFn) (interface{}, error) {
+	if len(layers) == 1 {
+		return loader(ctx)
+	}
+
+	next := reflect.New(reflect.TypeOf(ptr).Elem()).Interface()
+	n, err := c.getOrLoad(ctx, layers[1:], key, next, ttl, loader)
+	if err != nil || n < 0 {
+		return next, err
+	}
+
+	c.backfillTo(ctx, layers[:1], n, map[string]interface{}{key: next})
+	return nil, &backfilled{n: n, ptr: next}
+}
+
+// enabledLayers returns indexes of enable cache implements
+func (c *multiCaches) enabledLayers() []int {
+	layers := make([]int, 0, len(c.caches))
+	for i, _cache := range c.caches {
+		if _cache.IsEnable() {
+			layers = append(layers, i)
+		}
+	}
+
+	return layers
+}
+
+// enabled returns enable cache implements
+func (c *multiCaches) enabled() []cache.ContextCache {
+	caches := make([]cache.ContextCache, 0, len(c.caches))
+	for _, _cache := range c.caches {
+		if _cache.IsEnable() {
+			caches = append(caches, _cache)
+		}
+	}
+
+	return caches
+}
+
+// assign loaded value v to ptr
+func assign(ptr interface{}, v interface{}) error {
+	pv := reflect.ValueOf(ptr)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return fmt.Errorf("invalid ptr type: %T", ptr)
+	}
+
+	vv := reflect.ValueOf(v)
+	if !vv.IsValid() {
+		pv.Elem().Set(reflect.Zero(pv.Elem().Type()))
+		return nil
+	}
+	if vv.Type().AssignableTo(pv.Elem().Type()) {
+		pv.Elem().Set(vv)
+		return nil
+	}
+	if vv.Kind() == reflect.Ptr && !vv.IsNil() && vv.Elem().Type().AssignableTo(pv.Elem().Type()) {
+		pv.Elem().Set(vv.Elem())
 		return nil
 	}
 
-	return fn()
+	return fmt.Errorf("can not assign %T to %T", v, ptr)
 }
 
 // Delete cache in all implements
 func (c *multiCaches) Delete(key string) (ok bool, err error) {
-	for _, cache := range c.caches {
-		_ok, err := cache.Delete(key)
+	return c.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx cache in all implements
+func (c *multiCaches) DeleteCtx(ctx context.Context, key string) (ok bool, err error) {
+	err = c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		_ok, err := _cache.DeleteCtx(ctx, key)
 		ok = ok || _ok
 
-		if err != nil {
-			return ok, err
-		}
-	}
+		return err
+	})
 
-	return ok, nil
+	return ok, err
 }
 
 // Get first found cache in all implements
 func (c *multiCaches) IsExist(key string) (ok bool, err error) {
+	return c.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx gets first found cache in all implements
+func (c *multiCaches) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
 	for _, cache := range c.caches {
-		if ok, err = cache.IsExist(key); ok || err != nil {
+		if ok, err = cache.IsExistCtx(ctx, key); ok || err != nil {
 			return ok, err
 		}
 	}
@@ -80,29 +289,104 @@ func (c *multiCaches) IsExist(key string) (ok bool, err error) {
 	return false, nil
 }
 
-// Flush all implements
-func (c *multiCaches) Flush() (count int, err error) {
-	for _, cache := range c.caches {
-		_count, err := cache.Flush()
-		if err != nil {
-			return count, err
+// TTL of key in the first implement having it
+func (c *multiCaches) TTL(key string) (ttl time.Duration, err error) {
+	return c.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx of key in the first implement having it, which serves Get
+func (c *multiCaches) TTLCtx(ctx context.Context, key string) (ttl time.Duration, err error) {
+	for _, _cache := range c.caches {
+		ttl, err = _cache.TTLCtx(ctx, key)
+		if err == nil {
+			return ttl, nil
+		}
+
+		// Try next cache implement if missing cache or backend is failed
+		if err == cache.ErrMiss || (c.cf.OnError != cache.FailOnError && cache.IsBackendError(err)) {
+			continue
 		}
 
-		count = +_count
+		return 0, err
 	}
 
-	return count, nil
+	return 0, cache.ErrMiss
+}
+
+// Touch key in all implements
+func (c *multiCaches) Touch(key string, ttl int) (ok bool, err error) {
+	return c.TouchCtx(context.Background(), key, cache.Seconds(ttl))
+}
+
+// TouchCtx touches key in all implements, ok=true if key is found in an implement
+func (c *multiCaches) TouchCtx(ctx context.Context, key string, ttl time.Duration) (ok bool, err error) {
+	// Same jittered TTL is propagated to all implements
+	ttl = c.cf.TTLJitter.Apply(ttl)
+	err = c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		_ok, err := _cache.TouchCtx(ctx, key, ttl)
+		ok = ok || _ok
+
+		return err
+	})
+
+	return ok, err
+}
+
+// Flush all implements which support it
+func (c *multiCaches) Flush() (count int, err error) {
+	return c.FlushCtx(context.Background())
 }
 
-// Check all cache implements are ready or not
+// FlushCtx flushes all implements which support it, count is the total number of flushed entries
+func (c *multiCaches) FlushCtx(ctx context.Context) (count int, err error) {
+	err = c.each(len(c.caches), func(_cache cache.ContextCache) error {
+		_count, err := _cache.FlushCtx(ctx)
+		count += _count
+
+		if err == cache.NotSupportedErr {
+			return nil
+		}
+
+		return err
+	})
+
+	return count, err
+}
+
+// Check cache implements are ready or not by ReadyPolicy
 func (c *multiCaches) IsReady() (ok bool) {
-	for _, cache := range c.caches {
-		if ok = cache.IsReady(); !ok {
-			return false
+	return c.IsReadyCtx(context.Background())
+}
+
+// IsReadyCtx checks cache implements are ready or not by ReadyPolicy, e.g. BestEffort is ready if any enable implement is ready
+func (c *multiCaches) IsReadyCtx(ctx context.Context) (ok bool) {
+	enabled, failed := 0, 0
+	for _, _cache := range c.caches {
+		if !_cache.IsEnable() {
+			continue
+		}
+		enabled++
+
+		if !_cache.IsReadyCtx(ctx) {
+			// All implements must be ready by Strict policy, the next implements are not checked
+			if c.cf.ReadyPolicy == Strict {
+				return false
+			}
+			failed++
 		}
 	}
 
-	return true
+	return c.cf.ReadyPolicy.ok(enabled, failed)
+}
+
+// ReadyLayers reports readiness of each implement of New, see Readiness
+func (c *multiCaches) ReadyLayers(ctx context.Context) []bool {
+	ready := make([]bool, len(c.caches))
+	for i, _cache := range c.caches {
+		ready[i] = _cache.IsReadyCtx(ctx)
+	}
+
+	return ready
 }
 
 // IsEnable is true if there is an enable cache
@@ -116,18 +400,18 @@ func (c *multiCaches) IsEnable() (ok bool) {
 	return false
 }
 
-// Close all cache implements
+// Close all cache implements, errors of implements are returned as Errors
 func (c *multiCaches) Close() error {
-	var errS []string
-	for _, cache := range c.caches {
+	var errs Errors
+	for i, cache := range c.caches {
 		if err := cache.Close(); err != nil {
-			errS = append(errS, err.Error())
+			errs = append(errs, &LayerError{Layer: i, Err: err})
 		}
 	}
 
-	if len(errS) == 0 {
+	if len(errs) == 0 {
 		return nil
 	}
 
-	return fmt.Errorf("errors: %s", strings.Join(errS, ", "))
+	return errs
 }
@@ -1,15 +1,25 @@
 package multi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/codec"
 	"github.com/hoaitan/cache/local"
 	"github.com/hoaitan/cache/redis"
 	"github.com/hoaitan/cache/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestCacheImplement(t *testing.T) {
@@ -53,3 +63,690 @@ func TestCacheImplement(t *testing.T) {
 	}
 
 }
+
+func TestGet_BackendError(t *testing.T) {
+	brokenRedisCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: "localhost:1", // Nothing is listening
+		Timeout:  1,
+	}, "test")
+	localCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	err := localCache.Set("test:get:backend-error", 1, 0)
+	assert.Nil(t, err)
+
+	// Backend error of 1st layer is returned
+	c := New(brokenRedisCache, localCache)
+	cacheInt := 0
+	err = c.Get("test:get:backend-error", &cacheInt, nil)
+	assert.True(t, cache.IsBackendError(err))
+	assert.Equal(t, 0, cacheInt)
+
+	// Serve from the next layer
+	c = NewWithConfig(Config{OnError: cache.NextLayerOnError}, brokenRedisCache, localCache)
+	err = c.Get("test:get:backend-error", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Fall through to the miss cache function
+	isCalled := false
+	err = c.Get("test:get:backend-error:missing", &cacheInt, func() error {
+		isCalled = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, isCalled)
+}
+
+func TestTyped(t *testing.T) {
+	c := cache.NewTyped[int](New(
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+	))
+
+	v, err := c.GetOrLoad("test:typed", func() (int, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	v, found, err := c.Get("test:typed")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+}
+
+func TestGetOrLoad_Backfill(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	lowerCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	c := New(upperCache, lowerCache)
+
+	// Found in lower layer, upper layer is backfilled
+	err := lowerCache.Set("test:get-or-load:backfill", 1, 0)
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	err = c.GetOrLoad("test:get-or-load:backfill", &cacheInt, 0, func() (interface{}, error) {
+		return 2, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	cacheInt = 0
+	found, err := upperCache.Lookup("test:get-or-load:backfill", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Loaded value is cached in all layers
+	err = c.GetOrLoad("test:get-or-load:all", &cacheInt, 0, func() (interface{}, error) {
+		return 2, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cacheInt)
+
+	for _, layer := range []cache.Cache{upperCache, lowerCache} {
+		ok, err := layer.IsExist("test:get-or-load:all")
+		assert.True(t, ok)
+		assert.Nil(t, err)
+	}
+
+	// No layer, loaded value is assigned to ptr
+	err = New().GetOrLoad("test:get-or-load:empty", &cacheInt, 0, func() (interface{}, error) {
+		return 3, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, cacheInt)
+}
+
+func TestGet_Backfill(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := NewWithConfig(Config{BackfillTTL: []time.Duration{time.Second}}, upperCache, lowerCache)
+
+	// Found in lower layer, upper layer is backfilled with its TTL
+	err := lowerCache.Set("test:get:backfill", 1, 0)
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	err = c.Get("test:get:backfill", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	cacheInt = 0
+	found, err := upperCache.Lookup("test:get:backfill", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	time.Sleep(2 * time.Second)
+	ok, err := upperCache.IsExist("test:get:backfill")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Found keys of GetMany are backfilled
+	values, err := c.GetMany([]string{"test:get:backfill"}, func() interface{} { return new(int) })
+	assert.Nil(t, err)
+	assert.Len(t, values, 1)
+
+	ok, err = upperCache.IsExist("test:get:backfill")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestGet_BackfillRemainingTTL(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable:     true,
+		Size:       1000000,
+		DefaultTTL: 60,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := NewWithConfig(Config{BackfillRemainingTTL: true}, upperCache, lowerCache)
+
+	err := lowerCache.Set("test:get:backfill", 1, 10)
+	assert.Nil(t, err)
+	s.FastForward(5 * time.Second)
+
+	// Upper layer is backfilled with the remaining TTL of lower layer
+	cacheInt := 0
+	found, err := c.Lookup("test:get:backfill", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+
+	ttl, err := upperCache.TTL("test:get:backfill")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 3*time.Second && ttl <= 5*time.Second, "ttl: %s", ttl)
+
+	// TTL of the first layer having the key
+	ttl, err = c.TTL("test:get:backfill")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 3*time.Second && ttl <= 5*time.Second, "ttl: %s", ttl)
+
+	_, err = c.TTL("test:get:not-found")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	// Touch all layers
+	ok, err := c.Touch("test:get:backfill", 20)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Second, s.TTL("test:test:get:backfill"))
+
+	ttl, err = upperCache.TTL("test:get:backfill")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 18*time.Second, "ttl: %s", ttl)
+}
+
+func TestGetOrLoad_BackfillRemainingTTL(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := NewWithConfig(Config{BackfillRemainingTTL: true}, upperCache, lowerCache)
+	loader := func() (interface{}, error) {
+		return 2, nil
+	}
+
+	err := lowerCache.SetMany(map[string]interface{}{"test:load:1": 1, "test:load:2": 1}, 10)
+	assert.Nil(t, err)
+	s.FastForward(5 * time.Second)
+
+	// Found in lower layer, upper layer is backfilled with the remaining TTL instead of the load TTL
+	cacheInt := 0
+	err = c.GetOrLoad("test:load:1", &cacheInt, 3600, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	ttl, err := upperCache.TTL("test:load:1")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 3*time.Second && ttl <= 5*time.Second, "ttl: %s", ttl)
+
+	values, err := c.GetManyOrLoad([]string{"test:load:2", "test:load:3"}, func() interface{} { return new(int) }, 3600, func(keys []string) (map[string]interface{}, error) {
+		assert.Equal(t, []string{"test:load:3"}, keys)
+		return map[string]interface{}{"test:load:3": 2}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, *values["test:load:2"].(*int))
+	assert.Equal(t, 2, *values["test:load:3"].(*int))
+
+	ttl, err = upperCache.TTL("test:load:2")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 3*time.Second && ttl <= 5*time.Second, "ttl: %s", ttl)
+
+	// Loaded value is cached with the load TTL in all layers
+	ttl, err = upperCache.TTL("test:load:3")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 3590*time.Second, "ttl: %s", ttl)
+	assert.Equal(t, time.Hour, s.TTL("test:test:load:3"))
+}
+
+func TestIncr_Invalidate(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := New(upperCache, lowerCache)
+
+	// Counter is incremented in the last layer
+	value, err := c.Incr("test:incr", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), value)
+
+	// Counter is backfilled to the upper layer by Get
+	var cacheInt int64
+	err = c.Get("test:incr", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cacheInt)
+
+	// Increment invalidates the upper layer
+	value, err = c.Incr("test:incr", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), value)
+
+	ok, err := upperCache.IsExist("test:incr")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	err = c.Get("test:incr", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), cacheInt)
+}
+
+func TestFlush(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+		redis.New(redis.Config{
+			Enable:   true,
+			Endpoint: s.Addr(),
+			Timeout:  1,
+		}, "app"),
+		// Flush is not supported without key prefix
+		redis.New(redis.Config{
+			Enable:   true,
+			Endpoint: s.Addr(),
+			Timeout:  1,
+		}, ""),
+	)
+
+	err := c.SetMany(map[string]interface{}{"test:flush:1": 1, "test:flush:2": 2}, 0)
+	assert.Nil(t, err)
+
+	// Flushed entries of all layers are counted
+	count, err := c.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []string{"test:flush:1", "test:flush:2"}, s.Keys())
+}
+
+func TestKeys(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := New(upperCache, lowerCache)
+
+	err := c.Set("test:keys:1", 1, 0)
+	assert.Nil(t, err)
+	err = lowerCache.Set("test:keys:2", 2, 0)
+	assert.Nil(t, err)
+
+	// A key of several layers is iterated once
+	var keys []string
+	it := c.Keys("test:keys:")
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.Nil(t, it.Err())
+	assert.ElementsMatch(t, []string{"test:keys:1", "test:keys:2"}, keys)
+
+	// Iteration stops at an error of a layer
+	s.Close()
+	it = c.Keys("test:keys:")
+	assert.True(t, it.Next())
+	assert.Equal(t, "test:keys:1", it.Key())
+	assert.False(t, it.Next())
+	assert.True(t, cache.IsBackendError(it.Err()))
+}
+
+func TestWritePolicy(t *testing.T) {
+	newLocal := func() cache.Cache {
+		return local.New(local.Config{Enable: true, Size: 1000000})
+	}
+	s := miniredis.RunT(t)
+	redisCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	s.Close()
+
+	// Strict fails on the failed implement
+	err := New(newLocal(), redisCache).Set("test:policy", 1, 0)
+	var layerErr *LayerError
+	assert.True(t, errors.As(err, &layerErr))
+	assert.Equal(t, 1, layerErr.Layer)
+	assert.True(t, cache.IsBackendError(err))
+
+	// Best effort succeeds if an implement succeeds, the failed implement is reported
+	var tolerated []*LayerError
+	c := NewWithConfig(Config{
+		WritePolicy: BestEffort,
+		OnLayerError: func(err *LayerError) {
+			tolerated = append(tolerated, err)
+		},
+	}, newLocal(), redisCache)
+	err = c.Set("test:policy", 1, 0)
+	assert.Nil(t, err)
+	assert.Len(t, tolerated, 1)
+	assert.Equal(t, 1, tolerated[0].Layer)
+
+	ok, err := c.Delete("test:policy")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// Best effort fails if all implements fail
+	err = NewWithConfig(Config{WritePolicy: BestEffort}, redisCache, redisCache).Set("test:policy", 1, 0)
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Contains(t, err.Error(), "layer 0: ")
+	assert.Contains(t, err.Error(), "layer 1: ")
+
+	// Quorum succeeds if a majority of implements succeeds
+	err = NewWithConfig(Config{WritePolicy: Quorum}, newLocal(), redisCache).Set("test:policy", 1, 0)
+	assert.True(t, cache.IsBackendError(err))
+
+	err = NewWithConfig(Config{WritePolicy: Quorum}, newLocal(), newLocal(), redisCache).Set("test:policy", 1, 0)
+	assert.Nil(t, err)
+}
+
+func TestReadyPolicy(t *testing.T) {
+	s := miniredis.RunT(t)
+	caches := []cache.Cache{
+		local.New(local.Config{Enable: true, Size: 1000000}),
+		redis.New(redis.Config{
+			Enable:   true,
+			Endpoint: s.Addr(),
+			Timeout:  1,
+		}, "test"),
+	}
+	s.Close()
+
+	assert.False(t, New(caches...).IsReady())
+	assert.True(t, NewWithConfig(Config{ReadyPolicy: BestEffort}, caches...).IsReady())
+	assert.False(t, NewWithConfig(Config{ReadyPolicy: Quorum}, caches...).IsReady())
+
+	// Partial availability is reported by implement
+	c := New(caches...)
+	assert.Equal(t, []bool{true, false}, c.(Readiness).ReadyLayers(context.Background()))
+
+	// Strict does not check the next implements after a not ready one
+	var pingCount int32
+	c = New(caches[1], &readyCache{Cache: caches[0], pingCount: &pingCount})
+	assert.False(t, c.IsReady())
+	assert.Equal(t, int32(0), pingCount)
+
+	c = NewWithConfig(Config{ReadyPolicy: BestEffort}, caches[1], &readyCache{Cache: caches[0], pingCount: &pingCount})
+	assert.True(t, c.IsReady())
+	assert.Equal(t, int32(1), pingCount)
+
+	// Without enable implement, it is ready by all policies
+	disabled := local.New(local.Config{Enable: false})
+	for _, policy := range []Policy{Strict, BestEffort, Quorum} {
+		assert.True(t, NewWithConfig(Config{ReadyPolicy: policy}, disabled).IsReady())
+	}
+}
+
+// readyCache counts IsReady calls of Cache
+type readyCache struct {
+	cache.Cache
+	pingCount *int32
+}
+
+func (c *readyCache) IsReady() bool {
+	atomic.AddInt32(c.pingCount, 1)
+	return c.Cache.IsReady()
+}
+
+func TestGet_AsyncBackfill(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	lowerCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	c := NewWithConfig(Config{AsyncBackfill: true}, upperCache, lowerCache)
+
+	err := lowerCache.Set("test:lookup:backfill", []int{1, 2}, 0)
+	assert.Nil(t, err)
+
+	var cacheInts []int
+	found, err := c.Lookup("test:lookup:backfill", &cacheInts)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, cacheInts)
+
+	// ptr is changed by caller after returning
+	cacheInts = nil
+
+	assert.Eventually(t, func() bool {
+		var v []int
+		found, err := upperCache.Lookup("test:lookup:backfill", &v)
+		return found && err == nil && assert.Equal(t, []int{1, 2}, v)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGet_AsyncBackfill_Proto(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+		Codec:  codec.Proto,
+	})
+	lowerCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+		Codec:  codec.Proto,
+	})
+	c := NewWithConfig(Config{AsyncBackfill: true}, upperCache, lowerCache)
+
+	err := lowerCache.Set("test:lookup:backfill", wrapperspb.String("value"), 0)
+	assert.Nil(t, err)
+
+	msg := &wrapperspb.StringValue{}
+	found, err := c.Lookup("test:lookup:backfill", msg)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, "value", msg.GetValue())
+
+	// Backfilled value is still a proto message
+	assert.Eventually(t, func() bool {
+		v := &wrapperspb.StringValue{}
+		found, err := upperCache.Lookup("test:lookup:backfill", v)
+		return found && err == nil && v.GetValue() == "value"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGetManyOrLoad_Backfill(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	s := miniredis.RunT(t)
+	lowerCache := redis.New(redis.Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	c := New(upperCache, lowerCache)
+	newInt := func() interface{} { return new(int) }
+
+	err := upperCache.Set("test:many:1", 1, 0)
+	assert.Nil(t, err)
+	err = lowerCache.SetMany(map[string]interface{}{"test:many:1": -1, "test:many:2": 2}, 0)
+	assert.Nil(t, err)
+
+	// Each layer is only asked for remaining missing keys, final missing keys are loaded
+	found, err := c.GetManyOrLoad([]string{"test:many:1", "test:many:2", "test:many:3"}, newInt, 0, func(keys []string) (map[string]interface{}, error) {
+		assert.Equal(t, []string{"test:many:3"}, keys)
+		return map[string]interface{}{"test:many:3": 3}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, *found["test:many:1"].(*int))
+	assert.Equal(t, 2, *found["test:many:2"].(*int))
+	assert.Equal(t, 3, *found["test:many:3"].(*int))
+
+	// Found keys of lower layer are backfilled to upper layer, loaded keys are cached in all layers
+	found, err = upperCache.GetMany([]string{"test:many:2", "test:many:3"}, newInt)
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+
+	ok, err := lowerCache.IsExist("test:many:3")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// GetMany also backfills without loading
+	_, err = upperCache.Delete("test:many:2")
+	assert.Nil(t, err)
+
+	found, err = c.GetMany([]string{"test:many:2", "test:many:4"}, newInt)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, 2, *found["test:many:2"].(*int))
+
+	ok, err = upperCache.IsExist("test:many:2")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestGetOrLoad_Coalesce(t *testing.T) {
+	c := NewWithConfig(Config{Coalesce: true},
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+	)
+	var loadCount int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			cacheInt := 0
+			err := c.GetOrLoad("test:get-or-load:coalesce", &cacheInt, 0, func() (interface{}, error) {
+				atomic.AddInt32(&loadCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, cacheInt)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loadCount)
+}
+
+func TestGetOrLoad_StaleWhileRevalidate(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable:   true,
+		Size:     1000000,
+		StaleTTL: 10 * time.Second,
+	})
+	lowerCache := local.New(local.Config{
+		Enable:   true,
+		Size:     1000000,
+		StaleTTL: 10 * time.Second,
+	})
+	c := New(upperCache, lowerCache)
+	var loadCount int32
+	loader := func() (interface{}, error) {
+		return int(atomic.AddInt32(&loadCount, 1)), nil
+	}
+
+	cacheInt := 0
+	err := c.GetOrLoad("test:get-or-load:stale", &cacheInt, 1, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Stale value of the upper layer is served, both layers are refreshed in background
+	time.Sleep(1100 * time.Millisecond)
+	err = c.GetOrLoad("test:get-or-load:stale", &cacheInt, 1, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	assert.Eventually(t, func() bool {
+		found, err := c.Lookup("test:get-or-load:stale", &cacheInt)
+		return found && err == nil && cacheInt == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loadCount))
+}
+
+func TestLookup_EarlyExpiration(t *testing.T) {
+	upperCache := local.New(local.Config{
+		Enable:          true,
+		Size:            1000000,
+		EarlyExpiration: 1,
+		Rand: func() float64 {
+			return math.Exp(-1000)
+		},
+	})
+	lowerCache := local.New(local.Config{
+		Enable: true,
+		Size:   1000000,
+	})
+	c := New(upperCache, lowerCache)
+
+	cacheInt := 0
+	err := c.GetOrLoad("test:lookup:early", &cacheInt, 10, func() (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	})
+	assert.Nil(t, err)
+
+	// Early expired in the upper layer, found in the lower layer
+	found, err := upperCache.Lookup("test:lookup:early", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+
+	cacheInt = 0
+	found, err = c.Lookup("test:lookup:early", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+}
+
+func TestCacheImplement_Jitter(t *testing.T) {
+	jitter := test.NewJitter(3 * time.Second)
+	c := NewWithConfig(Config{TTLJitter: jitter.Config()},
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+		local.New(local.Config{
+			Enable: true,
+			Size:   1000000,
+		}),
+	)
+
+	for _, fn := range test.GetTestSuite(true, jitter) {
+		t.Run(fmt.Sprintf("fn=%s", runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()), func(t *testing.T) {
+			fn(t, c)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package multi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hoaitan/cache"
+)
+
+// Policy decides whether errors of implements are fatal for an operation on all implements
+type Policy int
+
+const (
+	// Strict fails on the first implement error, the following implements are not written (default)
+	Strict Policy = iota
+
+	// BestEffort writes all implements and succeeds if any enable implement succeeds
+	BestEffort
+
+	// Quorum writes all implements and succeeds if a majority of enable implements succeeds
+	Quorum
+)
+
+// ok is true if failed of n enable implements are tolerated, it is true without enable implement
+func (p Policy) ok(n int, failed int) bool {
+	if n == 0 {
+		return true
+	}
+
+	switch p {
+	case BestEffort:
+		return failed < n
+	case Quorum:
+		return n-failed > n/2
+	}
+
+	return failed == 0
+}
+
+// LayerError is an error of the implement at index Layer of New
+type LayerError struct {
+	Layer int
+	Err   error
+}
+
+func (e *LayerError) Error() string {
+	return fmt.Sprintf("layer %d: %v", e.Layer, e.Err)
+}
+
+func (e *LayerError) Unwrap() error {
+	return e.Err
+}
+
+// Errors of implements which failed an operation, errors.Is and errors.As match any of them,
+// e.g. cache.IsBackendError is true if an implement has a backend error
+type Errors []*LayerError
+
+func (e Errors) Error() string {
+	errS := make([]string, 0, len(e))
+	for _, err := range e {
+		errS = append(errS, err.Error())
+	}
+
+	return strings.Join(errS, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Readiness is implemented by caches of New to report partial availability, e.g. c.(multi.Readiness).ReadyLayers(ctx)
+type Readiness interface {
+	// ReadyLayers reports readiness of each implement by its index of New
+	ReadyLayers(ctx context.Context) []bool
+}
+
+// each runs fn on the first n enable implements, implement errors are aggregated and tolerated by WritePolicy
+func (c *multiCaches) each(n int, fn func(_cache cache.ContextCache) error) error {
+	var errs Errors
+	enabled := 0
+	for i, _cache := range c.caches[:n] {
+		if !_cache.IsEnable() {
+			continue
+		}
+		enabled++
+
+		if err := fn(_cache); err != nil {
+			errs = append(errs, &LayerError{Layer: i, Err: err})
+			if c.cf.WritePolicy == Strict {
+				return errs
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if !c.cf.WritePolicy.ok(enabled, len(errs)) {
+		return errs
+	}
+
+	// Operation is done in degraded mode
+	if c.cf.OnLayerError != nil {
+		for _, err := range errs {
+			c.cf.OnLayerError(err)
+		}
+	}
+
+	return nil
+}
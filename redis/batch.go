@@ -0,0 +1,229 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/envelope"
+)
+
+func (c *redisCache) GetMany(keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	return c.GetManyCtx(context.Background(), keys, factory)
+}
+
+// GetManyCtx gets cached values of keys by one MGET (a pipeline of GET in Cluster), stale values are missing
+func (c *redisCache) GetManyCtx(ctx context.Context, keys []string, factory cache.PtrFactory) (found map[string]interface{}, err error) {
+	found = make(map[string]interface{}, len(keys))
+	if !c.enabled() || len(keys) == 0 {
+		return found, nil
+	}
+
+	values, err := c.getMany(ctx, keys)
+	if err != nil {
+		// Backend failure is only treated as missing cache by LoadOnError policy
+		if c.cf.OnError == cache.LoadOnError {
+			return found, nil
+		}
+
+		return found, err
+	}
+
+	for key, v := range values {
+		ptr := factory()
+		if err = c.codec.Unmarshal(v, ptr); err != nil {
+			return found, err
+		}
+		found[key] = ptr
+	}
+
+	return found, nil
+}
+
+func (c *redisCache) GetManyOrLoad(keys []string, factory cache.PtrFactory, ttl int, loader cache.LoadManyFn) (found map[string]interface{}, err error) {
+	return c.GetManyOrLoadCtx(context.Background(), keys, factory, cache.Seconds(ttl), loader.WithContext())
+}
+
+// GetManyOrLoadCtx gets cached values of keys, missing values are loaded at once by loader and cached by one pipeline
+func (c *redisCache) GetManyOrLoadCtx(ctx context.Context, keys []string, factory cache.PtrFactory, ttl time.Duration, loader cache.LoadManyCtxFn) (found map[string]interface{}, err error) {
+	found, err = c.GetManyCtx(ctx, keys, factory)
+	if err != nil {
+		return found, err
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 || loader == nil {
+		return found, nil
+	}
+
+	// Load missing cache
+	start := time.Now()
+	values, err := loader(ctx, missing)
+	if err != nil {
+		return found, err
+	}
+	delta := time.Since(start)
+
+	encoded := make(map[string][]byte, len(values))
+	for _, key := range missing {
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		// Decode loaded value the same way as cached value
+		b, err := c.codec.Marshal(v)
+		if err != nil {
+			return found, err
+		}
+		ptr := factory()
+		if err = c.codec.Unmarshal(b, ptr); err != nil {
+			return found, err
+		}
+		found[key] = ptr
+		encoded[key] = b
+	}
+
+	if !c.enabled() {
+		return found, nil
+	}
+
+	// found is already filled, backend failure is ignored by LoadOnError policy
+	err = c.setMany(ctx, encoded, ttl, delta)
+	if c.cf.OnError == cache.LoadOnError && cache.IsBackendError(err) {
+		return found, nil
+	}
+
+	return found, err
+}
+
+func (c *redisCache) SetMany(items map[string]interface{}, ttl int) (err error) {
+	return c.SetManyCtx(context.Background(), items, cache.Seconds(ttl))
+}
+
+// SetManyCtx sets items by one pipeline
+func (c *redisCache) SetManyCtx(ctx context.Context, items map[string]interface{}, ttl time.Duration) (err error) {
+	if !c.IsEnable() {
+		return nil
+	}
+
+	encoded := make(map[string][]byte, len(items))
+	for key, data := range items {
+		if encoded[key], err = c.codec.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	return c.setMany(ctx, encoded, ttl, 0)
+}
+
+func (c *redisCache) DeleteMany(keys []string) (count int, err error) {
+	return c.DeleteManyCtx(context.Background(), keys)
+}
+
+// DeleteManyCtx deletes keys by one DEL (one DEL per key in Cluster), count is the number of deleted keys
+func (c *redisCache) DeleteManyCtx(ctx context.Context, keys []string) (count int, err error) {
+	if !c.IsEnable() || len(keys) == 0 {
+		return 0, nil
+	}
+
+	redisKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redisKeys = append(redisKeys, c.getKey(key))
+	}
+
+	count, err = retry(ctx, c, func() (int, error) {
+		return c.countKeys(ctx, c.cacheEngine, redisKeys, redisv8.Cmdable.Del)
+	})
+
+	return count, c.wrapErr(err)
+}
+
+func (c *redisCache) DeleteByPrefix(prefix string) (count int, err error) {
+	return c.DeleteByPrefixCtx(context.Background(), prefix)
+}
+
+// DeleteByPrefixCtx scans keys starting with prefix under the key prefix and unlinks them batch by batch like FlushCtx.
+// Without key prefix, an empty prefix is not supported because it would delete the whole Redis database.
+func (c *redisCache) DeleteByPrefixCtx(ctx context.Context, prefix string) (count int, err error) {
+	if !c.IsEnable() {
+		return 0, nil
+	}
+	if c.keyPrefix == "" && prefix == "" {
+		return 0, cache.NotSupportedErr
+	}
+
+	return c.unlinkMatch(ctx, patternEscaper.Replace(c.getKey(prefix))+"*")
+}
+
+// setMany sets encoded values by one pipeline
+func (c *redisCache) setMany(ctx context.Context, encoded map[string][]byte, ttl time.Duration, delta time.Duration) error {
+	if len(encoded) == 0 {
+		return nil
+	}
+
+	type item struct {
+		b          []byte
+		expiration time.Duration
+	}
+	items := make(map[string]item, len(encoded))
+	for key, b := range encoded {
+		delta := delta
+		if delta == 0 {
+			delta = c.recomputes.Delta(key)
+		}
+
+		b, expiration := c.encode(b, ttl, delta)
+		items[c.getKey(key)] = item{b: b, expiration: expiration}
+	}
+
+	_, err := retry(ctx, c, func() ([]redisv8.Cmder, error) {
+		return c.cacheEngine.Pipelined(ctx, func(pipe redisv8.Pipeliner) error {
+			for redisKey, item := range items {
+				pipe.Set(ctx, redisKey, item.b, item.expiration)
+			}
+
+			return nil
+		})
+	})
+
+	return c.wrapErr(err)
+}
+
+// getMany returns fresh encoded values of found keys
+func (c *redisCache) getMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	redisKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redisKeys = append(redisKeys, c.getKey(key))
+	}
+
+	values, err := retry(ctx, c, func() ([]interface{}, error) {
+		return c.mget(ctx, redisKeys)
+	})
+	if err != nil {
+		return nil, c.wrapErr(err)
+	}
+
+	now := time.Now()
+	found := make(map[string][]byte, len(keys))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		e := envelope.Decode([]byte(s))
+		if e.IsStale(now, c.cf.EarlyExpiration, c.rand) {
+			continue
+		}
+		found[keys[i]] = e.Payload
+	}
+
+	return found, nil
+}
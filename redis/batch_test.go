@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:     true,
+		Endpoint:   s.Addr(),
+		Timeout:    1,
+		DefaultTTL: 60,
+	}, "test")
+	newInt := func() interface{} { return new(int) }
+
+	// Values are set under key prefix with TTL
+	err := c.SetMany(map[string]interface{}{"test:many:1": 1, "test:many:2": 2}, 10)
+	assert.Nil(t, err)
+	v, err := s.Get("test:test:many:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	assert.Equal(t, 10*time.Second, s.TTL("test:test:many:2"))
+
+	// Loaded values are cached with default TTL
+	found, err := c.GetManyOrLoad([]string{"test:many:1", "test:many:3"}, newInt, -1, func(keys []string) (map[string]interface{}, error) {
+		return map[string]interface{}{"test:many:3": 3}, nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, 60*time.Second, s.TTL("test:test:many:3"))
+
+	// Values are stale after TTL
+	s.FastForward(10 * time.Second)
+	found, err = c.GetMany([]string{"test:many:1", "test:many:2", "test:many:3"}, newInt)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, 3, *found["test:many:3"].(*int))
+
+	// Backend failure
+	s.Close()
+	_, err = c.GetMany([]string{"test:many:3"}, newInt)
+	assert.True(t, cache.IsBackendError(err))
+
+	_, err = c.DeleteMany([]string{"test:many:3"})
+	assert.True(t, cache.IsBackendError(err))
+}
@@ -0,0 +1,175 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCoolDown  = 10 * time.Second
+)
+
+// ErrBreakerOpen is returned as a backend error while the circuit breaker is open
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+type BreakerConfig struct {
+	Enable        bool
+	Threshold     int                         // Consecutive connection failures which open the breaker, default: 5
+	CoolDown      time.Duration               // Open time before one trial command is let through, default: 10s
+	MissOnOpen    bool                        // Reads miss while open (loaded values are not cached) instead of failing fast with ErrBreakerOpen, writes still fail
+	OnStateChange func(from, to BreakerState) // Called on state changes, e.g. to alert or to route around the cache
+}
+
+// BreakerState of the circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Commands are sent to Redis
+	BreakerOpen                         // Commands fail fast until CoolDown is over
+	BreakerHalfOpen                     // One trial command is sent, it closes the breaker on success and opens it again on failure
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+// breaker counts consecutive connection failures of commands as a go-redis hook, nil if it is disabled
+type breaker struct {
+	cf       BreakerConfig
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool // Trial command of half-open state is running
+}
+
+func newBreaker(cf BreakerConfig) *breaker {
+	if !cf.Enable {
+		return nil
+	}
+	if cf.Threshold <= 0 {
+		cf.Threshold = defaultBreakerThreshold
+	}
+	if cf.CoolDown <= 0 {
+		cf.CoolDown = defaultBreakerCoolDown
+	}
+
+	return &breaker{cf: cf}
+}
+
+// isOpen is true if a command would be rejected now
+func (b *breaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return time.Since(b.openedAt) < b.cf.CoolDown
+	case BreakerHalfOpen:
+		return b.trial
+	}
+
+	return false
+}
+
+// allow a command, ErrBreakerOpen if it is rejected
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+
+	switch {
+	case b.state == BreakerOpen && time.Since(b.openedAt) >= b.cf.CoolDown:
+		b.state = BreakerHalfOpen
+		b.trial = true
+	case b.state == BreakerHalfOpen && !b.trial:
+		b.trial = true
+	case b.state != BreakerClosed:
+		b.mu.Unlock()
+		return ErrBreakerOpen
+	}
+
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	return nil
+}
+
+// record the result of an allowed command
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	from := b.state
+
+	switch {
+	case isConnErr(err):
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.cf.Threshold {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	case isReply(err):
+		b.failures = 0
+		b.state = BreakerClosed
+	}
+	b.trial = false
+
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// notify OnStateChange outside the lock, so it can call IsReady
+func (b *breaker) notify(from, to BreakerState) {
+	if from != to && b.cf.OnStateChange != nil {
+		b.cf.OnStateChange(from, to)
+	}
+}
+
+func (b *breaker) BeforeProcess(ctx context.Context, cmd redisv8.Cmder) (context.Context, error) {
+	return ctx, b.allow()
+}
+
+func (b *breaker) AfterProcess(ctx context.Context, cmd redisv8.Cmder) error {
+	// Rejected command was not sent
+	if !errors.Is(cmd.Err(), ErrBreakerOpen) {
+		b.record(cmd.Err())
+	}
+
+	return nil
+}
+
+func (b *breaker) BeforeProcessPipeline(ctx context.Context, cmds []redisv8.Cmder) (context.Context, error) {
+	return ctx, b.allow()
+}
+
+func (b *breaker) AfterProcessPipeline(ctx context.Context, cmds []redisv8.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = cmd.Err(); isConnErr(err) || errors.Is(err, ErrBreakerOpen) {
+			break
+		}
+	}
+
+	// Rejected pipeline was not sent
+	if !errors.Is(err, ErrBreakerOpen) {
+		b.record(err)
+	}
+
+	return nil
+}
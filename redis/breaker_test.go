@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	var mu sync.Mutex
+	var changes []string
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		Breaker: BreakerConfig{
+			Enable:    true,
+			Threshold: 2,
+			CoolDown:  50 * time.Millisecond,
+			OnStateChange: func(from, to BreakerState) {
+				mu.Lock()
+				defer mu.Unlock()
+				changes = append(changes, from.String()+" -> "+to.String())
+			},
+		},
+	}, "test")
+
+	err := c.Set("breaker", 1, 0)
+	assert.Nil(t, err)
+
+	// Breaker is open after consecutive connection failures
+	s.Close()
+	for i := 0; i < 2; i++ {
+		err = c.Set("breaker", 1, 0)
+		assert.True(t, cache.IsBackendError(err))
+		assert.False(t, errors.Is(err, ErrBreakerOpen))
+	}
+
+	err = c.Set("breaker", 1, 0)
+	assert.True(t, cache.IsBackendError(err))
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+	assert.False(t, c.IsReady())
+
+	// Failed trial command opens it again
+	time.Sleep(60 * time.Millisecond)
+	err = c.Set("breaker", 1, 0)
+	assert.False(t, errors.Is(err, ErrBreakerOpen))
+	err = c.Set("breaker", 1, 0)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+
+	// Succeeded trial command closes it
+	assert.Nil(t, s.Restart())
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, c.IsReady())
+
+	found, err := c.Lookup("not-found", new(int))
+	assert.False(t, found)
+	assert.Nil(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, changes)
+}
+
+func TestBreaker_MissOnOpen(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		Breaker: BreakerConfig{
+			Enable:     true,
+			Threshold:  1,
+			CoolDown:   time.Minute,
+			MissOnOpen: true,
+		},
+	}, "test")
+
+	s.Close()
+	err := c.Set("breaker", 1, 0)
+	assert.True(t, cache.IsBackendError(err))
+
+	// Open breaker behaves as a disabled cache
+	assert.True(t, c.IsEnable())
+	assert.False(t, c.IsReady())
+
+	// Writes fail fast, a dropped invalidation is reported
+	err = c.Set("breaker", 1, 0)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+
+	ok, err := c.Delete("breaker")
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+
+	_, err = c.Flush()
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+
+	// Reads miss
+	missCount := 0
+	err = c.Get("breaker", new(int), func() error {
+		missCount++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, missCount)
+
+	cacheInt := 0
+	err = c.GetOrLoad("breaker", &cacheInt, 0, func() (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+}
+
+func TestRetry(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:          true,
+		Endpoint:        s.Addr(),
+		Timeout:         1,
+		MaxRetries:      5,
+		MinRetryBackoff: 20 * time.Millisecond,
+		MaxRetryBackoff: 40 * time.Millisecond,
+	}, "test")
+
+	restart := func() {
+		s.Close()
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			assert.Nil(t, s.Restart())
+		}()
+	}
+
+	// Idempotent command is retried until Redis is back
+	restart()
+	err := c.Set("retry", 1, 0)
+	assert.Nil(t, err)
+	assert.True(t, s.Exists("test:retry"))
+
+	// Counter is not retried, it could be incremented twice
+	restart()
+	_, err = c.Incr("retry:counter", 1, 0)
+	assert.True(t, cache.IsBackendError(err))
+
+	time.Sleep(50 * time.Millisecond)
+	value, err := c.Incr("retry:counter", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), value)
+}
@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/internal/envelope"
+)
+
+func (c *redisCache) SetNX(key string, data interface{}, ttl int) (ok bool, err error) {
+	return c.SetNXCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+// SetNXCtx sets key by SET NX, a stale value is replaced in a WATCH transaction
+func (c *redisCache) SetNXCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (ok bool, err error) {
+	if !c.IsEnable() {
+		return false, nil
+	}
+
+	b, err := c.codec.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+	b, expiration := c.encode(b, ttl, c.recomputes.Delta(key))
+
+	if !c.useEnvelope() {
+		ok, err = c.cacheEngine.SetNX(ctx, c.getKey(key), b, expiration).Result()
+		return ok, c.wrapErr(err)
+	}
+
+	// Redis keeps stale value, it is missing for SetNX
+	return c.swap(ctx, key, b, expiration, func(v []byte, found bool) bool {
+		return !found
+	})
+}
+
+func (c *redisCache) CompareAndSwap(key string, old, new interface{}, ttl int) (ok bool, err error) {
+	return c.CompareAndSwapCtx(context.Background(), key, old, new, cache.Seconds(ttl))
+}
+
+// CompareAndSwapCtx compares encoded values then sets key in a WATCH transaction, ok=false if key is changed concurrently
+func (c *redisCache) CompareAndSwapCtx(ctx context.Context, key string, old, new interface{}, ttl time.Duration) (ok bool, err error) {
+	if !c.IsEnable() {
+		return false, nil
+	}
+
+	oldB, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newB, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+	newB, expiration := c.encode(newB, ttl, c.recomputes.Delta(key))
+
+	return c.swap(ctx, key, newB, expiration, func(v []byte, found bool) bool {
+		return found && bytes.Equal(v, oldB)
+	})
+}
+
+// swap sets encoded value b if cond of the current value of key is true, in a WATCH transaction.
+// A stale value is missing for cond, early expiration is ignored for conditional writes.
+func (c *redisCache) swap(ctx context.Context, key string, b []byte, expiration time.Duration, cond func(v []byte, found bool) bool) (ok bool, err error) {
+	ok, err = c.update(ctx, key, func(e envelope.Envelope, found bool) ([]byte, time.Duration, bool) {
+		return b, expiration, cond(e.Payload, found)
+	})
+
+	// Key is changed concurrently
+	if err == redisv8.TxFailedErr {
+		return false, nil
+	}
+
+	return ok, c.wrapErr(err)
+}
+
+// update sets encoded value b returned by fn of the current envelope of key if ok, in a WATCH transaction.
+// A stale value is missing for fn, redisv8.TxFailedErr is returned if key is changed concurrently.
+func (c *redisCache) update(ctx context.Context, key string, fn func(e envelope.Envelope, found bool) (b []byte, expiration time.Duration, ok bool)) (ok bool, err error) {
+	redisKey := c.getKey(key)
+	err = c.cacheEngine.Watch(ctx, func(tx *redisv8.Tx) error {
+		v, err := tx.Get(ctx, redisKey).Bytes()
+		if err != nil && err != redisv8.Nil {
+			return err
+		}
+
+		var e envelope.Envelope
+		found := err == nil
+		if found {
+			e = envelope.Decode(v)
+			found = !e.IsStale(time.Now(), 0, nil)
+		}
+		b, expiration, _ok := fn(e, found)
+		if !_ok {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redisv8.Pipeliner) error {
+			pipe.Set(ctx, redisKey, b, expiration)
+			return nil
+		})
+		ok = err == nil
+
+		return err
+	}, redisKey)
+
+	return ok, err
+}
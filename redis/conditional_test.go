@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache/internal/envelope"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetNX_Stale(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		StaleTTL: 10 * time.Second,
+	}, "test")
+
+	err := c.Set("test:set-nx", 1, 1)
+	assert.Nil(t, err)
+
+	ok, err := c.SetNX("test:set-nx", 2, 1)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Stale value kept by Redis is missing for SetNX
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = c.SetNX("test:set-nx", 2, 1)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// Stale value is not swapped
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = c.CompareAndSwap("test:set-nx", 2, 3, 1)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	found, err := c.Lookup("test:set-nx", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+}
+
+func TestUpdate_Concurrent(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		StaleTTL: 10 * time.Second,
+	}, "test")
+
+	err := c.Set("test:touch", 1, 10)
+	assert.Nil(t, err)
+
+	// Value set concurrently is not overwritten by the old value
+	ok, err := c.(*redisCache).update(context.Background(), "test:touch", func(e envelope.Envelope, found bool) ([]byte, time.Duration, bool) {
+		assert.True(t, found)
+		assert.Nil(t, c.Set("test:touch", 2, 10))
+
+		return e.Encode(), time.Minute, true
+	})
+	assert.False(t, ok)
+	assert.Equal(t, redisv8.TxFailedErr, err)
+
+	ok, err = c.Touch("test:touch", 20)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	found, err := c.Lookup("test:touch", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cacheInt)
+	assert.Equal(t, 30*time.Second, s.TTL("test:test:touch"))
+}
+
+func TestConditional_Concurrent(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, staleTTL := range []time.Duration{0, 10 * time.Second} {
+		// SetNX by SET NX without envelope, by WATCH transaction with envelope
+		c := New(Config{
+			Enable:   true,
+			Endpoint: s.Addr(),
+			Timeout:  1,
+			StaleTTL: staleTTL,
+		}, "test")
+		s.FlushAll()
+
+		// Only one of concurrent SetNX sets the key
+		var wg sync.WaitGroup
+		var setCount int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ok, err := c.SetNX("test:set-nx", i, 10)
+				assert.Nil(t, err)
+				if ok {
+					atomic.AddInt32(&setCount, 1)
+				}
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), setCount, "staleTTL: %s", staleTTL)
+
+		// Concurrent read-modify-write cycles don't lose updates
+		err := c.Set("test:cas", 0, 10)
+		assert.Nil(t, err)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					cacheInt := 0
+					if _, err := c.Lookup("test:cas", &cacheInt); err != nil {
+						assert.Nil(t, err)
+						return
+					}
+
+					ok, err := c.CompareAndSwap("test:cas", cacheInt, cacheInt+1, 10)
+					if err != nil {
+						assert.Nil(t, err)
+						return
+					}
+					if ok {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		cacheInt := 0
+		found, err := c.Lookup("test:cas", &cacheInt)
+		assert.True(t, found)
+		assert.Nil(t, err)
+		assert.Equal(t, 20, cacheInt, "staleTTL: %s", staleTTL)
+	}
+}
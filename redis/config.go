@@ -1,8 +1,168 @@
 package redis
 
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hoaitan/cache"
+)
+
 type Config struct {
-	Enable     bool
-	Endpoint   string
-	Timeout    int // in seconds
-	DefaultTTL int // in seconds
+	Enable             bool
+	Topology           Topology          // Standalone, Sentinel or Cluster, default: Standalone
+	Endpoint           string            // Address of Standalone
+	Addrs              []string          // Sentinel addresses of Sentinel, seed addresses of Cluster, default: Endpoint
+	MasterName         string            // Master name of Sentinel
+	HashTag            bool              // Wrap key prefix in a hash tag, e.g. "{prefix}:key", all keys are in one cluster slot for multi-key commands
+	TLS                TLSConfig         // Connect by TLS if TLS.Enable
+	Username           string            // ACL username, default: the default user
+	Password           string            // Password of Username or requirepass
+	PasswordFile       string            // Read Password from a file, e.g. a mounted secret
+	PasswordEnv        string            // Read Password from an environment variable
+	DB                 int               // Logical database of Standalone and Sentinel, Cluster only has DB 0
+	Timeout            int               // in seconds, Deprecated: use ConnTimeout
+	ConnTimeout        time.Duration     // Dial, read and write timeout, overrides Timeout
+	DialTimeout        time.Duration     // Timeout of new connections, default: ConnTimeout
+	ReadTimeout        time.Duration     // Timeout of socket reads, default: ConnTimeout
+	WriteTimeout       time.Duration     // Timeout of socket writes, default: ConnTimeout
+	PoolSize           int               // Max connections (per node in Cluster), default: 10 per CPU (5 per CPU in Cluster)
+	MinIdleConns       int               // Idle connections kept open to avoid dialing on bursts
+	MaxConnAge         time.Duration     // Close connections older than MaxConnAge, default: never
+	PoolTimeout        time.Duration     // Max wait for a free connection when all are busy, default: ReadTimeout + 1s
+	IdleTimeout        time.Duration     // Close connections idle for IdleTimeout, default: 5m
+	IdleCheckFrequency time.Duration     // Interval of closing idle connections, default: 1m
+	MaxRetries         int               // Retries of idempotent commands on connection failures, default: 0
+	MinRetryBackoff    time.Duration     // Backoff before the first retry, doubled by each retry, default: 8ms
+	MaxRetryBackoff    time.Duration     // Max backoff between retries, default: 512ms
+	Breaker            BreakerConfig     // Circuit breaker which fails fast after consecutive connection failures
+	DefaultTTL         int               // in seconds, Deprecated: use DefaultExpiration
+	DefaultExpiration  time.Duration     // Default TTL in milliseconds precision, overrides DefaultTTL
+	TTLJitter          cache.Jitter      // Jitter added to TTL, includes DefaultTTL
+	StaleTTL           time.Duration     // Serve stale value for StaleTTL after TTL by GetOrLoad only, which refreshes it in background. Get and Lookup treat it as a miss
+	EarlyExpiration    float64           // XFetch beta, > 0 expires values early with a probability weighted by their recompute time, 1 is a good default
+	Rand               func() float64    // Random source in (0, 1] of early expiration, default: math/rand
+	Coalesce           bool              // Coalesce concurrent loads of a missing key, only one loader runs per key
+	LockLoad           bool              // Lock a missing key in Redis, only one process loads it and the others wait for its value
+	LockTTL            time.Duration     // Load lock expiry, default: 5s
+	LockWait           time.Duration     // Max wait for the lock holder before loading by itself, default: LockTTL
+	LockPollInterval   time.Duration     // Interval to poll the value cached by the lock holder, default: 50ms
+	FlushBatchSize     int               // Keys scanned per batch by Flush, DeleteByPrefix and Keys, default: 1000
+	FlushInterval      time.Duration     // Pause between batches of Flush to limit the load on Redis
+	Codec              cache.Codec       // default: codec.JSON
+	OnError            cache.ErrorPolicy // Get behavior on backend errors, default: cache.FailOnError (NextLayerOnError is applied by multi cache)
+}
+
+// defaultTTL is DefaultExpiration, DefaultTTL if it is not set
+func (cf Config) defaultTTL() time.Duration {
+	if cf.DefaultExpiration > 0 {
+		return cf.DefaultExpiration
+	}
+
+	return time.Duration(cf.DefaultTTL) * time.Second
+}
+
+// addrs is Addrs, Endpoint if it is not set
+func (cf Config) addrs() []string {
+	if len(cf.Addrs) > 0 {
+		return cf.Addrs
+	}
+
+	return []string{cf.Endpoint}
+}
+
+// timeout is ConnTimeout, Timeout if it is not set
+func (cf Config) timeout() time.Duration {
+	if cf.ConnTimeout > 0 {
+		return cf.ConnTimeout
+	}
+
+	return time.Duration(cf.Timeout) * time.Second
+}
+
+// credentials resolves the password and loads the TLS config
+func (cf Config) credentials() (password string, tlsConfig *tls.Config, err error) {
+	if cf.Topology == Cluster && cf.DB != 0 {
+		return "", nil, fmt.Errorf("DB %d is not supported by Cluster", cf.DB)
+	}
+
+	if password, err = cf.password(); err != nil {
+		return "", nil, err
+	}
+
+	if tlsConfig, err = cf.TLS.config(); err != nil {
+		return "", nil, err
+	}
+
+	return password, tlsConfig, nil
+}
+
+// password is Password, the content of PasswordFile or the value of PasswordEnv in this order
+func (cf Config) password() (string, error) {
+	switch {
+	case cf.Password != "":
+		return cf.Password, nil
+	case cf.PasswordFile != "":
+		b, err := os.ReadFile(cf.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+
+		// Trailing new line of secret files is not a part of the password
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case cf.PasswordEnv != "":
+		password, ok := os.LookupEnv(cf.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", cf.PasswordEnv)
+		}
+
+		return password, nil
+	}
+
+	return "", nil
+}
+
+// dialTimeout is DialTimeout, timeout if it is not set
+func (cf Config) dialTimeout() time.Duration {
+	if cf.DialTimeout > 0 {
+		return cf.DialTimeout
+	}
+
+	return cf.timeout()
+}
+
+// readTimeout is ReadTimeout, timeout if it is not set
+func (cf Config) readTimeout() time.Duration {
+	if cf.ReadTimeout > 0 {
+		return cf.ReadTimeout
+	}
+
+	return cf.timeout()
+}
+
+// writeTimeout is WriteTimeout, timeout if it is not set
+func (cf Config) writeTimeout() time.Duration {
+	if cf.WriteTimeout > 0 {
+		return cf.WriteTimeout
+	}
+
+	return cf.timeout()
+}
+
+func (cf Config) minRetryBackoff() time.Duration {
+	if cf.MinRetryBackoff > 0 {
+		return cf.MinRetryBackoff
+	}
+
+	return defaultMinRetryBackoff
+}
+
+func (cf Config) maxRetryBackoff() time.Duration {
+	if cf.MaxRetryBackoff > 0 {
+		return cf.MaxRetryBackoff
+	}
+
+	return defaultMaxRetryBackoff
 }
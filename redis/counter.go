@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache"
+)
+
+// incrScript increments a counter, a missing counter is created with expiration in milliseconds
+var incrScript = redisv8.NewScript(`
+local created = redis.call("EXISTS", KEYS[1]) == 0
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if created and tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+func (c *redisCache) Incr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.IncrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// IncrCtx increments the counter by INCRBY, the counter is a Redis integer which only JSON codec can read by Get
+func (c *redisCache) IncrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	if !c.IsEnable() {
+		return 0, nil
+	}
+
+	value, err = incrScript.Run(ctx, c.cacheEngine, []string{c.getKey(key)}, delta, c.expiration(ttl).Milliseconds()).Int64()
+
+	return value, c.wrapErr(err)
+}
+
+func (c *redisCache) Decr(key string, delta int64, ttl int) (value int64, err error) {
+	return c.DecrCtx(context.Background(), key, delta, cache.Seconds(ttl))
+}
+
+// DecrCtx decrements the counter by INCRBY
+func (c *redisCache) DecrCtx(ctx context.Context, key string, delta int64, ttl time.Duration) (value int64, err error) {
+	return c.IncrCtx(ctx, key, -delta, ttl)
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncr(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := cache.WithContext(New(Config{
+		Enable:     true,
+		Endpoint:   s.Addr(),
+		Timeout:    1,
+		DefaultTTL: 60,
+	}, "test"))
+	ctx := context.Background()
+
+	// Counter is a Redis integer created with expiration in milliseconds
+	value, err := c.IncrCtx(ctx, "test:incr", 5, 1500*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), value)
+	assert.Equal(t, 1500*time.Millisecond, s.TTL("test:test:incr"))
+
+	v, err := s.Get("test:test:incr")
+	assert.Nil(t, err)
+	assert.Equal(t, "5", v)
+
+	// Expiration is not extended
+	s.FastForward(time.Second)
+	value, err = c.DecrCtx(ctx, "test:incr", 2, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), value)
+	assert.Equal(t, 500*time.Millisecond, s.TTL("test:test:incr"))
+
+	// Expired counter is created again with default TTL
+	s.FastForward(time.Second)
+	value, err = c.IncrCtx(ctx, "test:incr", 1, cache.DefaultTTL)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), value)
+	assert.Equal(t, time.Minute, s.TTL("test:test:incr"))
+
+	// Backend failure
+	s.Close()
+	_, err = c.IncrCtx(ctx, "test:incr", 1, cache.DefaultTTL)
+	assert.True(t, cache.IsBackendError(err))
+}
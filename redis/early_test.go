@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarlyExpiration(t *testing.T) {
+	s := miniredis.RunT(t)
+	var rnd atomic.Value
+	rnd.Store(1.0)
+	c := New(Config{
+		Enable:          true,
+		Endpoint:        s.Addr(),
+		Timeout:         1,
+		EarlyExpiration: 1,
+		Rand: func() float64 {
+			return rnd.Load().(float64)
+		},
+	}, "test")
+
+	var loadCount int32
+	loader := func() (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return int(atomic.AddInt32(&loadCount, 1)), nil
+	}
+
+	cacheInt := 0
+	err := c.GetOrLoad("test:early", &cacheInt, 10, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// rnd=1: never expires early
+	err = c.GetOrLoad("test:early", &cacheInt, 10, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loadCount))
+
+	// rnd=e^-1000: early expired value is served and refreshed in background
+	rnd.Store(math.Exp(-1000))
+	err = c.GetOrLoad("test:early", &cacheInt, 10, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	rnd.Store(1.0)
+	assert.Eventually(t, func() bool {
+		found, err := c.Lookup("test:early", &cacheInt)
+		return found && err == nil && cacheInt == 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlush(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:         true,
+		Endpoint:       s.Addr(),
+		Timeout:        1,
+		FlushBatchSize: 2,
+		FlushInterval:  time.Millisecond,
+	}, "test[1]")
+
+	for i := 0; i < 5; i++ {
+		err := c.Set(fmt.Sprintf("key:%d", i), i, 0)
+		assert.Nil(t, err)
+	}
+
+	// Keys outside the prefix are never touched
+	s.Set("other:key", "1")
+	s.Set("test1:key", "1")
+	s.Set("test[1]key", "1")
+
+	count, err := c.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, count)
+	assert.Equal(t, []string{"other:key", "test1:key", "test[1]key"}, s.Keys())
+
+	// Without key prefix
+	count, err = New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "").Flush()
+	assert.Equal(t, cache.NotSupportedErr, err)
+	assert.Equal(t, 0, count)
+	assert.Len(t, s.Keys(), 3)
+}
+
+func TestDeleteByPrefix(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:         true,
+		Endpoint:       s.Addr(),
+		Timeout:        1,
+		FlushBatchSize: 2,
+		LockLoad:       true,
+	}, "test")
+
+	for i := 0; i < 5; i++ {
+		err := c.Set(fmt.Sprintf("user:[1]:%d", i), i, 0)
+		assert.Nil(t, err)
+	}
+
+	// Keys outside the prefix are never touched
+	s.Set("user:[1]:0", "1")
+	s.Set("test:user:1:0", "1")
+	s.Set("test:user:[1]", "1")
+
+	// Load lock keys are deleted but not counted
+	s.Set("test:user:[1]:0"+lockSuffix, "token")
+
+	count, err := c.DeleteByPrefix("user:[1]:")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, count)
+	assert.Equal(t, []string{"test:user:1:0", "test:user:[1]", "user:[1]:0"}, s.Keys())
+
+	// Without LockLoad, keys with the lock suffix are cached keys
+	noLock := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	s.Set("test:user:[2]:0", "1")
+	s.Set("test:user:[2]:0"+lockSuffix, "1")
+
+	count, err = noLock.DeleteByPrefix("user:[2]:")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// Without key prefix, empty prefix is not supported
+	noPrefix := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "")
+	count, err = noPrefix.DeleteByPrefix("")
+	assert.Equal(t, cache.NotSupportedErr, err)
+	assert.Equal(t, 0, count)
+
+	count, err = noPrefix.DeleteByPrefix("user:")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Len(t, s.Keys(), 2)
+}
+
+func TestKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:         true,
+		Endpoint:       s.Addr(),
+		Timeout:        1,
+		FlushBatchSize: 2,
+		LockLoad:       true,
+	}, "test")
+
+	for i := 0; i < 5; i++ {
+		err := c.Set(fmt.Sprintf("user:%d", i), i, 0)
+		assert.Nil(t, err)
+	}
+
+	// Keys outside the prefix are not iterated
+	s.Set("user:5", "1")
+	s.Set("test:other", "1")
+
+	// Load lock keys are not iterated
+	s.Set("test:user:0"+lockSuffix, "token")
+
+	var keys []string
+	it := c.Keys("user:")
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.Nil(t, it.Err())
+	assert.ElementsMatch(t, []string{"user:0", "user:1", "user:2", "user:3", "user:4"}, keys)
+
+	// Without LockLoad, keys with the lock suffix are iterated
+	noLock := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+	}, "test")
+	keys = nil
+	it = noLock.Keys("user:0")
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.Nil(t, it.Err())
+	assert.ElementsMatch(t, []string{"user:0", "user:0" + lockSuffix}, keys)
+
+	// Scan error is returned as backend error
+	s.Close()
+	it = c.Keys("user:")
+	assert.False(t, it.Next())
+	assert.True(t, cache.IsBackendError(it.Err()))
+}
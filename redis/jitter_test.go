@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLJitter(t *testing.T) {
+	s := miniredis.RunT(t)
+	rnd := 0.5
+	c := New(Config{
+		Enable:     true,
+		Endpoint:   s.Addr(),
+		Timeout:    1,
+		DefaultTTL: 60,
+		TTLJitter: cache.Jitter{
+			Max:     2 * time.Second,
+			Percent: 10,
+			Rand: func() float64 {
+				return rnd
+			},
+		},
+	}, "test")
+
+	// Explicit TTL
+	err := c.Set("test:jitter", 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 11500*time.Millisecond, s.TTL("test:test:jitter"))
+
+	// Default TTL
+	err = c.Set("test:jitter:default", 1, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, 64*time.Second, s.TTL("test:test:jitter:default"))
+
+	// No expire
+	err = c.Set("test:jitter:no-expire", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), s.TTL("test:test:jitter:no-expire"))
+}
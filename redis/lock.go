@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache"
+)
+
+const (
+	lockSuffix              = ":__lock"
+	defaultLockTTL          = 5 * time.Second
+	defaultLockPollInterval = 50 * time.Millisecond
+)
+
+// unlockScript deletes the lock only if it is still held by the token
+var unlockScript = redisv8.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// lock acquires the load lock of key, ok=false if it is held by another process
+func (c *redisCache) lock(ctx context.Context, key string) (unlock func(), ok bool, err error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	lockKey := c.getKey(key) + lockSuffix
+	ok, err = c.cacheEngine.SetNX(ctx, lockKey, token, c.lockTTL()).Result()
+	if err != nil || !ok {
+		return nil, false, c.wrapErr(err)
+	}
+
+	unlock = func() {
+		// ctx can be already done, lock expires anyway if unlock is failed
+		unlockScript.Run(context.Background(), c.cacheEngine, []string{lockKey}, token)
+	}
+
+	return unlock, true, nil
+}
+
+// wait for the value of key cached by the lock holder, found=false if it doesn't appear in LockWait
+func (c *redisCache) wait(ctx context.Context, key string) (b []byte, found bool, err error) {
+	timeout := time.NewTimer(c.lockWait())
+	defer timeout.Stop()
+
+	interval := c.cf.LockPollInterval
+	if interval <= 0 {
+		interval = defaultLockPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-timeout.C:
+			return nil, false, nil
+		case <-ticker.C:
+			b, err = c.get(ctx, key)
+			if err == nil {
+				return b, true, nil
+			}
+			if err != cache.ErrMiss {
+				return nil, false, err
+			}
+		}
+	}
+}
+
+func (c *redisCache) lockTTL() time.Duration {
+	if c.cf.LockTTL <= 0 {
+		return defaultLockTTL
+	}
+
+	return c.cf.LockTTL
+}
+
+func (c *redisCache) lockWait() time.Duration {
+	if c.cf.LockWait <= 0 {
+		return c.lockTTL()
+	}
+
+	return c.cf.LockWait
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLockTestCaches(t *testing.T, cf Config, count int) (*miniredis.Miniredis, []cache.Cache) {
+	s := miniredis.RunT(t)
+
+	cf.Enable = true
+	cf.Endpoint = s.Addr()
+	cf.Timeout = 1
+	cf.LockLoad = true
+
+	// Each cache simulates a process
+	caches := make([]cache.Cache, count)
+	for i := range caches {
+		caches[i] = New(cf, "test")
+	}
+
+	return s, caches
+}
+
+func TestLockLoad(t *testing.T) {
+	_, caches := newLockTestCaches(t, Config{
+		LockPollInterval: 10 * time.Millisecond,
+	}, 10)
+	var loadCount, missCount int32
+
+	var wg sync.WaitGroup
+	for _, c := range caches {
+		c := c
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			cacheInt := 0
+			err := c.GetOrLoad("test:lock:load", &cacheInt, 0, func() (interface{}, error) {
+				atomic.AddInt32(&loadCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, cacheInt)
+		}()
+		go func() {
+			defer wg.Done()
+
+			cacheInt := 0
+			err := c.Get("test:lock:miss", &cacheInt, func() error {
+				atomic.AddInt32(&missCount, 1)
+				time.Sleep(100 * time.Millisecond)
+				cacheInt = 1
+				return c.Set("test:lock:miss", cacheInt, 0)
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, cacheInt)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loadCount)
+	assert.Equal(t, int32(1), missCount)
+}
+
+func TestLockLoad_Released(t *testing.T) {
+	s, caches := newLockTestCaches(t, Config{}, 1)
+
+	cacheInt := 0
+	err := caches[0].GetOrLoad("test:lock:released", &cacheInt, 0, func() (interface{}, error) {
+		// Lock is held while loading
+		assert.True(t, s.Exists("test:test:lock:released"+lockSuffix))
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+	assert.False(t, s.Exists("test:test:lock:released"+lockSuffix))
+}
+
+func TestLockLoad_Timeout(t *testing.T) {
+	s, caches := newLockTestCaches(t, Config{
+		LockWait:         50 * time.Millisecond,
+		LockPollInterval: 10 * time.Millisecond,
+	}, 1)
+
+	// Lock is held by a dead process
+	s.Set("test:test:lock:timeout"+lockSuffix, "token")
+
+	// Load by itself after timeout
+	cacheInt := 0
+	start := time.Now()
+	err := caches[0].GetOrLoad("test:lock:timeout", &cacheInt, 0, func() (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	// Lock of another process is not released
+	assert.True(t, s.Exists("test:test:lock:timeout"+lockSuffix))
+}
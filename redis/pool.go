@@ -0,0 +1,31 @@
+package redis
+
+// PoolStats are counters of the connection pool, summed over nodes in Cluster
+type PoolStats struct {
+	Hits     uint32 // Times a free connection was found in the pool
+	Misses   uint32 // Times a free connection was not found in the pool
+	Timeouts uint32 // Times PoolTimeout was reached waiting for a free connection, the pool is exhausted
+
+	TotalConns uint32 // Connections in the pool
+	IdleConns  uint32 // Idle connections in the pool
+	StaleConns uint32 // Stale connections removed from the pool
+}
+
+// PoolStater is implemented by caches of New, e.g. c.(redis.PoolStater).PoolStats()
+type PoolStater interface {
+	PoolStats() PoolStats
+}
+
+// PoolStats of the connection pool
+func (c *redisCache) PoolStats() PoolStats {
+	stats := c.cacheEngine.PoolStats()
+
+	return PoolStats{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		StaleConns: stats.StaleConns,
+	}
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolStats(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:      true,
+		Endpoint:    s.Addr(),
+		Timeout:     1,
+		PoolSize:    1,
+		PoolTimeout: 10 * time.Millisecond,
+	}, "test")
+
+	assert.True(t, c.IsReady())
+	assert.True(t, c.IsReady())
+	stats := c.(PoolStater).PoolStats()
+	assert.Equal(t, PoolStats{Hits: 1, Misses: 1, TotalConns: 1, IdleConns: 1}, stats)
+
+	// Pool is exhausted while the only connection is blocked
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		c.(*redisCache).cacheEngine.BLPop(context.Background(), time.Second, "list")
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	assert.False(t, c.IsReady())
+	stats = c.(PoolStater).PoolStats()
+	assert.Equal(t, uint32(1), stats.Timeouts)
+	assert.Equal(t, uint32(1), stats.TotalConns)
+	assert.Equal(t, uint32(0), stats.IdleConns)
+	<-blocked
+}
+
+func TestTimeouts(t *testing.T) {
+	cf := Config{Timeout: 3, ReadTimeout: time.Second}
+	assert.Equal(t, 3*time.Second, cf.dialTimeout())
+	assert.Equal(t, time.Second, cf.readTimeout())
+	assert.Equal(t, 3*time.Second, cf.writeTimeout())
+
+	cf = Config{ConnTimeout: 2 * time.Second, DialTimeout: 5 * time.Second, WriteTimeout: time.Second}
+	assert.Equal(t, 5*time.Second, cf.dialTimeout())
+	assert.Equal(t, 2*time.Second, cf.readTimeout())
+	assert.Equal(t, time.Second, cf.writeTimeout())
+}
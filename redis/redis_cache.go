@@ -2,106 +2,327 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"strings"
 	"time"
 
 	redisv8 "github.com/go-redis/redis/v8"
 	"github.com/hoaitan/cache"
+	"github.com/hoaitan/cache/codec"
+	"github.com/hoaitan/cache/internal/coalesce"
+	"github.com/hoaitan/cache/internal/envelope"
 )
 
 type redisCache struct {
-	cacheEngine *redisv8.Client
+	cacheEngine redisv8.UniversalClient
 	cf          Config
+	codec       cache.Codec
+	group       *coalesce.Group
+	refresher   *coalesce.Group
+	recomputes  *envelope.Recomputes
+	rand        func() float64
 	keyPrefix   string
+	breaker     *breaker
 }
 
 // New Redis cache
 func New(cf Config, keyPrefix string) cache.Cache {
-	return &redisCache{
-		cacheEngine: redisv8.NewClient(&redisv8.Options{
-			Addr:         cf.Endpoint,
-			DialTimeout:  time.Duration(cf.Timeout) * time.Second,
-			ReadTimeout:  time.Duration(cf.Timeout) * time.Second,
-			WriteTimeout: time.Duration(cf.Timeout) * time.Second,
-		}),
-		cf:        cf,
-		keyPrefix: strings.TrimRight(keyPrefix, ":"),
+	c := &redisCache{
+		cacheEngine: newClient(cf),
+		cf:          cf,
+		codec:       cf.Codec,
+		group:       coalesce.New(cf.Coalesce),
+		refresher:   coalesce.New(true),
+		recomputes:  &envelope.Recomputes{},
+		rand:        cf.Rand,
+		keyPrefix:   strings.TrimRight(keyPrefix, ":"),
+		breaker:     newBreaker(cf.Breaker),
 	}
+	if c.codec == nil {
+		c.codec = codec.JSON
+	}
+	if c.breaker != nil {
+		c.cacheEngine.AddHook(c.breaker)
+	}
+	if cf.HashTag && c.keyPrefix != "" {
+		c.keyPrefix = "{" + c.keyPrefix + "}"
+	}
+	if c.rand == nil {
+		c.rand = envelope.Rand
+	}
+
+	return c
 }
 
 func (c *redisCache) Set(key string, data interface{}, ttl int) (err error) {
+	return c.SetCtx(context.Background(), key, data, cache.Seconds(ttl))
+}
+
+func (c *redisCache) SetCtx(ctx context.Context, key string, data interface{}, ttl time.Duration) (err error) {
 	if !c.IsEnable() {
 		return nil
 	}
-	if ttl < 0 {
-		ttl = c.cf.DefaultTTL
-	}
 
 	// Encode data
-	data, err = json.Marshal(data)
+	b, err := c.codec.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	// Set value to cache engine
-	return c.cacheEngine.Set(context.Background(), c.getKey(key), data, time.Duration(ttl)*time.Second).Err()
+	return c.set(ctx, key, b, ttl, c.recomputes.Delta(key))
 }
 
 func (c *redisCache) Get(key string, ptr interface{}, fn cache.MissCacheFn) (err error) {
-	if !c.IsEnable() {
-		return fn()
+	return c.GetCtx(context.Background(), key, ptr, fn.WithContext())
+}
+
+func (c *redisCache) GetCtx(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) (err error) {
+	found, err := c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	// Call function if missing cache
+	if fn == nil {
+		return nil
+	}
+
+	return c.miss(ctx, key, ptr, fn)
+}
+
+func (c *redisCache) Lookup(key string, ptr interface{}) (found bool, err error) {
+	return c.LookupCtx(context.Background(), key, ptr)
+}
+
+func (c *redisCache) LookupCtx(ctx context.Context, key string, ptr interface{}) (found bool, err error) {
+	if !c.enabled() {
+		return false, nil
 	}
 
 	// Get cached value
-	v, err := c.cacheEngine.Get(context.Background(), c.getKey(key)).Result()
+	v, err := c.get(ctx, key)
+	if err == cache.ErrMiss {
+		return false, nil
+	}
 	if err != nil {
-		// Call function if missing cache
-		if fn == nil {
-			return nil
+		// Backend failure is only treated as missing cache by LoadOnError policy
+		if c.cf.OnError == cache.LoadOnError {
+			return false, nil
 		}
-		return fn()
+
+		return false, err
 	}
 
 	// Decode
-	if err = json.Unmarshal([]byte(v), ptr); err != nil {
-		return err
+	if err = c.codec.Unmarshal(v, ptr); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+func (c *redisCache) GetOrLoad(key string, ptr interface{}, ttl int, loader cache.LoadFn) (err error) {
+	return c.GetOrLoadCtx(context.Background(), key, ptr, cache.Seconds(ttl), loader.WithContext())
+}
+
+func (c *redisCache) GetOrLoadCtx(ctx context.Context, key string, ptr interface{}, ttl time.Duration, loader cache.LoadCtxFn) (err error) {
+	if c.enabled() {
+		v, isStale, err := c.getStale(ctx, key)
+		switch {
+		case err == nil && !isStale:
+			return c.codec.Unmarshal(v, ptr)
+		case err == nil && !envelope.IsRefresh(ctx):
+			// Stale value is served while it is refreshed in background
+			c.refresh(key, ttl, loader)
+			return c.codec.Unmarshal(v, ptr)
+		case err != nil && err != cache.ErrMiss && c.cf.OnError != cache.LoadOnError:
+			// Backend failure is only treated as missing cache by LoadOnError policy
+			return err
+		}
+	}
+
+	// Load missing cache
+	v, err := c.group.Load(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.load(ctx, key, ttl, loader)
+	})
+
+	// Decode loaded value the same way as cached value
+	b, _ := v.([]byte)
+	if b != nil {
+		if err := c.codec.Unmarshal(b, ptr); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	// ptr is already filled, backend failure is ignored by LoadOnError policy
+	if b != nil && c.cf.OnError == cache.LoadOnError && cache.IsBackendError(err) {
+		return nil
+	}
+
+	return err
 }
 
 func (c *redisCache) Delete(key string) (ok bool, err error) {
+	return c.DeleteCtx(context.Background(), key)
+}
+
+func (c *redisCache) DeleteCtx(ctx context.Context, key string) (ok bool, err error) {
 	if !c.IsEnable() {
 		return false, nil
 	}
 
-	count, err := c.cacheEngine.Del(context.Background(), c.getKey(key)).Result()
+	count, err := retry(ctx, c, func() (int64, error) {
+		return c.cacheEngine.Del(ctx, c.getKey(key)).Result()
+	})
 
-	return count > 0, err
+	return count > 0, c.wrapErr(err)
 }
 
 func (c *redisCache) IsExist(key string) (ok bool, err error) {
+	return c.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx is false for a stale enveloped value, the same as conditional writes
+func (c *redisCache) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
+	if !c.enabled() {
+		return false, nil
+	}
+
+	if c.useEnvelope() {
+		b, err := c.getBytes(ctx, key)
+		if err == redisv8.Nil {
+			return false, nil
+		}
+		if err != nil {
+			return false, c.wrapErr(err)
+		}
+
+		return !envelope.Decode(b).IsStale(time.Now(), 0, nil), nil
+	}
+
+	count, err := retry(ctx, c, func() (int64, error) {
+		return c.cacheEngine.Exists(ctx, c.getKey(key)).Result()
+	})
+
+	return count > 0, c.wrapErr(err)
+}
+
+func (c *redisCache) TTL(key string) (ttl time.Duration, err error) {
+	return c.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx returns the remaining fresh time of an enveloped value, PTTL of key otherwise
+func (c *redisCache) TTLCtx(ctx context.Context, key string) (ttl time.Duration, err error) {
+	if !c.enabled() {
+		return 0, cache.ErrMiss
+	}
+
+	if c.useEnvelope() {
+		b, err := c.getBytes(ctx, key)
+		if err == redisv8.Nil {
+			return 0, cache.ErrMiss
+		}
+		if err != nil {
+			return 0, c.wrapErr(err)
+		}
+
+		if e := envelope.Decode(b); !e.FreshUntil.IsZero() {
+			if ttl = time.Until(e.FreshUntil); ttl <= 0 {
+				return 0, cache.ErrMiss
+			}
+
+			return ttl, nil
+		}
+	}
+
+	ttl, err = retry(ctx, c, func() (time.Duration, error) {
+		return c.cacheEngine.PTTL(ctx, c.getKey(key)).Result()
+	})
+	if err != nil {
+		return 0, c.wrapErr(err)
+	}
+
+	switch ttl {
+	case -2: // Key does not exist
+		return 0, cache.ErrMiss
+	case -1: // Key has no expiration
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (c *redisCache) Touch(key string, ttl int) (ok bool, err error) {
+	return c.TouchCtx(context.Background(), key, cache.Seconds(ttl))
+}
+
+// TouchCtx resets TTL of key by PEXPIRE, an enveloped value is set again with new fresh time
+func (c *redisCache) TouchCtx(ctx context.Context, key string, ttl time.Duration) (ok bool, err error) {
 	if !c.IsEnable() {
 		return false, nil
 	}
 
-	count, err := c.cacheEngine.Exists(context.Background(), c.getKey(key)).Result()
+	if c.useEnvelope() {
+		for i := 0; i < touchAttempts; i++ {
+			// Value is set again in a WATCH transaction, so a concurrent write is not overwritten by the old value
+			ok, err = c.update(ctx, key, func(e envelope.Envelope, found bool) ([]byte, time.Duration, bool) {
+				// Stale value is not touched, it is missing for Get
+				if !found {
+					return nil, 0, false
+				}
+
+				b, expiration := c.encode(e.Payload, ttl, e.Delta)
+				return b, expiration, true
+			})
+			if err != redisv8.TxFailedErr {
+				return ok, c.wrapErr(err)
+			}
+		}
+
+		// Key is changed concurrently at every attempt, the last write wins
+		return false, nil
+	}
+
+	expiration := c.expiration(ttl)
+	n, err := retry(ctx, c, func() (int, error) {
+		return touchScript.Run(ctx, c.cacheEngine, []string{c.getKey(key)}, expiration.Milliseconds()).Int()
+	})
 
-	return count > 0, err
+	return n > 0, c.wrapErr(err)
 }
 
-// Because performance issue, don't support this feature
+// Flush keys under the key prefix, see FlushCtx
 func (c *redisCache) Flush() (count int, err error) {
-	return 0, cache.NotSupportedErr
+	return c.FlushCtx(context.Background())
+}
+
+// FlushCtx scans keys under the key prefix and unlinks them batch by batch, keys outside the prefix are never touched.
+// Without key prefix, it is not supported because it would flush the whole Redis database.
+func (c *redisCache) FlushCtx(ctx context.Context) (count int, err error) {
+	if !c.IsEnable() {
+		return 0, nil
+	}
+	if c.keyPrefix == "" {
+		return 0, cache.NotSupportedErr
+	}
+
+	return c.unlinkMatch(ctx, patternEscaper.Replace(c.keyPrefix)+":*")
 }
 
 func (c *redisCache) IsReady() bool {
+	return c.IsReadyCtx(context.Background())
+}
+
+func (c *redisCache) IsReadyCtx(ctx context.Context) bool {
 	if !c.IsEnable() {
 		return true
 	}
 
-	if _, err := c.cacheEngine.Ping(context.Background()).Result(); err != nil {
+	// Open breaker is not ready, a ping of half-open breaker is its trial command
+	if c.breaker.isOpen() {
+		return false
+	}
+
+	if _, err := c.cacheEngine.Ping(ctx).Result(); err != nil {
 		return false
 	}
 
@@ -112,6 +333,12 @@ func (c *redisCache) IsEnable() bool {
 	return c.cf.Enable
 }
 
+// enabled is IsEnable for reads and sets of loaded values, false while the breaker is open if the cache behaves as a miss.
+// Other writes fail with ErrBreakerOpen, so dropped invalidations are not silent.
+func (c *redisCache) enabled() bool {
+	return c.IsEnable() && !(c.cf.Breaker.MissOnOpen && c.breaker.isOpen())
+}
+
 func (c *redisCache) Close() error {
 	if !c.IsEnable() {
 		return nil
@@ -120,6 +347,206 @@ func (c *redisCache) Close() error {
 	return c.cacheEngine.Close()
 }
 
+// miss calls fn for missing cache, concurrent callers of a coalesced key look up the cache set by fn of the first caller
+func (c *redisCache) miss(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) error {
+	// Track recompute time of fn for early expiration
+	if c.cf.EarlyExpiration > 0 {
+		defer c.recomputes.Start(key)()
+	}
+
+	isShared, err := c.group.Miss(key, func() error {
+		return c.lockMiss(ctx, key, ptr, fn)
+	})
+	if !isShared || err != nil {
+		return err
+	}
+
+	found, err := c.LookupCtx(ctx, key, ptr)
+	if err != nil || found {
+		return err
+	}
+
+	return fn(ctx)
+}
+
+// lockMiss calls fn if this process holds the load lock, otherwise waits for the value cached by the lock holder
+func (c *redisCache) lockMiss(ctx context.Context, key string, ptr interface{}, fn cache.MissCacheCtxFn) error {
+	if !c.cf.LockLoad || !c.enabled() {
+		return fn(ctx)
+	}
+
+	unlock, ok, err := c.lock(ctx, key)
+	if err != nil && c.cf.OnError != cache.LoadOnError {
+		return err
+	}
+	if ok {
+		defer unlock()
+	}
+	if ok || err != nil {
+		return fn(ctx)
+	}
+
+	// Wait for the lock holder, load by itself on timeout
+	b, found, err := c.wait(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fn(ctx)
+	}
+
+	return c.codec.Unmarshal(b, ptr)
+}
+
+// load and cache missing value, returns the encoded value
+func (c *redisCache) load(ctx context.Context, key string, ttl time.Duration, loader cache.LoadCtxFn) ([]byte, error) {
+	// Missing value is not loaded without loader
+	if loader == nil {
+		return nil, cache.ErrMiss
+	}
+
+	if c.cf.LockLoad && c.enabled() {
+		unlock, ok, err := c.lock(ctx, key)
+		if err != nil && c.cf.OnError != cache.LoadOnError {
+			return nil, err
+		}
+		if ok {
+			defer unlock()
+		}
+
+		// Wait for the lock holder, load by itself on timeout
+		if !ok && err == nil {
+			b, found, err := c.wait(ctx, key)
+			if err != nil || found {
+				return b, err
+			}
+		}
+	}
+
+	start := time.Now()
+	v, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delta := time.Since(start)
+
+	b, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.enabled() {
+		return b, nil
+	}
+
+	return b, c.set(ctx, key, b, ttl, delta)
+}
+
+// refresh stale value of key in background, only one refresh runs per key
+func (c *redisCache) refresh(key string, ttl time.Duration, loader cache.LoadCtxFn) {
+	ctx := envelope.RefreshContext(context.Background())
+	go c.refresher.Load(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.load(ctx, key, ttl, loader)
+	})
+}
+
+func (c *redisCache) set(ctx context.Context, key string, b []byte, ttl time.Duration, delta time.Duration) error {
+	b, expiration := c.encode(b, ttl, delta)
+
+	// Set value to cache engine
+	_, err := retry(ctx, c, func() (string, error) {
+		return c.cacheEngine.Set(ctx, c.getKey(key), b, expiration).Result()
+	})
+
+	return c.wrapErr(err)
+}
+
+// encode returns the value and expiration to store encoded value b with ttl
+func (c *redisCache) encode(b []byte, ttl time.Duration, delta time.Duration) ([]byte, time.Duration) {
+	expiration := c.expiration(ttl)
+
+	// Keep stale value for StaleTTL after it expires, keep recompute time for early expiration
+	if c.useEnvelope() && expiration > 0 {
+		b = envelope.Envelope{
+			FreshUntil: time.Now().Add(expiration),
+			Delta:      delta,
+			Payload:    b,
+		}.Encode()
+		expiration += c.cf.StaleTTL
+	}
+
+	return b, expiration
+}
+
+// expiration resolves default TTL of ttl and applies jitter
+func (c *redisCache) expiration(ttl time.Duration) time.Duration {
+	if ttl < 0 {
+		ttl = c.cf.defaultTTL()
+	}
+
+	return c.cf.TTLJitter.Apply(ttl)
+}
+
+// useEnvelope is true if values are enveloped to become stale before Redis expires them
+func (c *redisCache) useEnvelope() bool {
+	return c.cf.StaleTTL > 0 || c.cf.EarlyExpiration > 0
+}
+
+// touchAttempts is the max number of WATCH transactions of Touch on concurrent writes of an enveloped value
+const touchAttempts = 3
+
+// touchScript sets expiration of an existing key in milliseconds, 0 removes its expiration
+var touchScript = redisv8.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+if tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+else
+	redis.call("PERSIST", KEYS[1])
+end
+return 1
+`)
+
+// get returns cache.ErrMiss if key is not found or stale, other errors are backend errors
+func (c *redisCache) get(ctx context.Context, key string) ([]byte, error) {
+	v, isStale, err := c.getStale(ctx, key)
+	if err == nil && isStale {
+		return nil, cache.ErrMiss
+	}
+
+	return v, err
+}
+
+// getStale is get, isStale=true if the value is only kept for StaleTTL
+func (c *redisCache) getStale(ctx context.Context, key string) (v []byte, isStale bool, err error) {
+	b, err := c.getBytes(ctx, key)
+	if err == redisv8.Nil {
+		return nil, false, cache.ErrMiss
+	}
+	if err != nil {
+		return nil, false, c.wrapErr(err)
+	}
+
+	e := envelope.Decode(b)
+	return e.Payload, e.IsStale(time.Now(), c.cf.EarlyExpiration, c.rand), nil
+}
+
+// getBytes of key by GET, redis.Nil if it is not found
+func (c *redisCache) getBytes(ctx context.Context, key string) ([]byte, error) {
+	return retry(ctx, c, func() ([]byte, error) {
+		return c.cacheEngine.Get(ctx, c.getKey(key)).Bytes()
+	})
+}
+
+func (c *redisCache) wrapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &cache.BackendError{Backend: "redis", Err: err}
+}
+
 func (c *redisCache) getKey(key string) string {
 	if c.keyPrefix == "" {
 		return key
@@ -1,52 +1,82 @@
 package redis
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
 	"github.com/hoaitan/cache/test"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCacheImplement_Enable(t *testing.T) {
+	s := miniredis.RunT(t)
 	c := New(Config{
 		Enable:     true,
-		Endpoint:   "localhost:6379",
+		Endpoint:   s.Addr(),
 		Timeout:    60,
 		DefaultTTL: 60,
 	}, "test")
 
-	// Is Redis ready for testing
-	if !c.IsReady() {
-		fmt.Println("Redis is not ready for testing. Exist!!!")
-		return
-	}
-
 	for _, fn := range test.GetTestSuite(true) {
-		t.Run(fmt.Sprintf("fn=%s", runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()), func(t *testing.T) {
+		name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+		t.Run(fmt.Sprintf("fn=%s", name), func(t *testing.T) {
+			// miniredis only expires keys by FastForward, expiry is tested in ttl_test.go
+			if strings.HasSuffix(name, ".testSet") || strings.HasSuffix(name, ".testIsExist") {
+				t.Skip("expiry by sleep is not supported by miniredis")
+			}
+
 			fn(t, c)
 		})
 	}
 }
 
 func TestCacheImplement_Disable(t *testing.T) {
+	s := miniredis.RunT(t)
 	c := New(Config{
 		Enable:     false,
-		Endpoint:   "localhost:6379",
+		Endpoint:   s.Addr(),
 		Timeout:    60,
 		DefaultTTL: 60,
 	}, "test")
 
-	// Is Redis ready for testing
-	if !c.IsReady() {
-		fmt.Println("Redis is not ready for testing. Exist!!!")
-		return
-	}
-
 	for _, fn := range test.GetTestSuite(false) {
 		t.Run(fmt.Sprintf("fn=%s", runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()), func(t *testing.T) {
 			fn(t, c)
 		})
 	}
 }
+
+func TestGet_BackendError(t *testing.T) {
+	cf := Config{
+		Enable:   true,
+		Endpoint: "localhost:1", // Nothing is listening
+		Timeout:  1,
+	}
+
+	isCalled := false
+	missFn := func() error {
+		isCalled = true
+		return nil
+	}
+
+	// Backend error is returned without calling miss cache function
+	c := New(cf, "test")
+	cacheInt := 0
+	err := c.Get("test:get:backend-error", &cacheInt, missFn)
+	assert.True(t, cache.IsBackendError(err))
+	assert.False(t, errors.Is(err, cache.ErrMiss))
+	assert.False(t, isCalled)
+
+	// Backend error is treated as missing cache
+	cf.OnError = cache.LoadOnError
+	c = New(cf, "test")
+	err = c.Get("test:get:backend-error", &cacheInt, missFn)
+	assert.Nil(t, err)
+	assert.True(t, isCalled)
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultMinRetryBackoff = 8 * time.Millisecond
+	defaultMaxRetryBackoff = 512 * time.Millisecond
+)
+
+// retry an idempotent command fn MaxRetries times on connection failures with exponential backoff
+func retry[T any](ctx context.Context, c *redisCache, fn func() (T, error)) (T, error) {
+	backoff := c.cf.minRetryBackoff()
+	for attempt := 0; ; attempt++ {
+		v, err := fn()
+		if attempt >= c.cf.MaxRetries || !isConnErr(err) {
+			return v, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return v, err
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > c.cf.maxRetryBackoff() {
+			backoff = c.cf.maxRetryBackoff()
+		}
+	}
+}
+
+// isConnErr reports whether err is a connection failure (refused, reset, timeout...), replies of Redis are not
+func isConnErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isReply reports whether err is a reply of Redis, including redis.Nil, so Redis is reachable
+func isReply(err error) bool {
+	var replyErr redisv8.Error
+	return err == nil || errors.As(err, &replyErr)
+}
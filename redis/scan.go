@@ -0,0 +1,186 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/hoaitan/cache"
+)
+
+const defaultFlushBatchSize = 1000
+
+// patternEscaper escapes glob characters of a literal in a SCAN MATCH pattern
+var patternEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// unlinkMatch scans keys matching pattern on all masters and unlinks them batch by batch, count is the number of unlinked keys
+func (c *redisCache) unlinkMatch(ctx context.Context, pattern string) (count int, err error) {
+	masters, err := c.masters(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, client := range masters {
+		n, err := c.unlinkNode(ctx, client, pattern)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// unlinkNode scans keys matching pattern on a node and unlinks them batch by batch
+func (c *redisCache) unlinkNode(ctx context.Context, client redisv8.UniversalClient, pattern string) (count int, err error) {
+	var cursor uint64
+	for {
+		scan, err := retry(ctx, c, func() (*redisv8.ScanCmd, error) {
+			cmd := client.Scan(ctx, cursor, pattern, int64(c.flushBatchSize()))
+			return cmd, cmd.Err()
+		})
+		if err != nil {
+			return count, c.wrapErr(err)
+		}
+		keys, next := scan.Val()
+
+		keys, lockKeys := c.splitLockKeys(keys)
+		if len(keys) > 0 {
+			n, err := c.unlink(ctx, client, keys)
+			count += n
+			if err != nil {
+				return count, err
+			}
+		}
+
+		// Load lock keys are unlinked but not counted, they are not cached keys
+		if len(lockKeys) > 0 {
+			if _, err := c.unlink(ctx, client, lockKeys); err != nil {
+				return count, err
+			}
+		}
+
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+
+		// Limit the rate of batches
+		if c.cf.FlushInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return count, ctx.Err()
+			case <-time.After(c.cf.FlushInterval):
+			}
+		}
+	}
+}
+
+// unlink keys on a node, count is the number of unlinked keys
+func (c *redisCache) unlink(ctx context.Context, client redisv8.UniversalClient, keys []string) (count int, err error) {
+	count, err = retry(ctx, c, func() (int, error) {
+		return c.countKeys(ctx, client, keys, redisv8.Cmdable.Unlink)
+	})
+
+	return count, c.wrapErr(err)
+}
+
+// splitLockKeys splits load lock keys from keys if LockLoad is enable, otherwise all keys are cache keys
+func (c *redisCache) splitLockKeys(keys []string) (cacheKeys []string, lockKeys []string) {
+	if !c.cf.LockLoad {
+		return keys, nil
+	}
+
+	cacheKeys = make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasSuffix(key, lockSuffix) {
+			lockKeys = append(lockKeys, key)
+		} else {
+			cacheKeys = append(cacheKeys, key)
+		}
+	}
+
+	return cacheKeys, lockKeys
+}
+
+func (c *redisCache) Keys(prefix string) cache.Iterator {
+	return c.KeysCtx(context.Background(), prefix)
+}
+
+// KeysCtx scans keys starting with prefix under the key prefix batch by batch, master by master in Cluster,
+// keys are returned without the key prefix
+func (c *redisCache) KeysCtx(ctx context.Context, prefix string) cache.Iterator {
+	if !c.enabled() {
+		return &keyIterator{}
+	}
+
+	masters, err := c.masters(ctx)
+
+	return &keyIterator{
+		c:       c,
+		ctx:     ctx,
+		masters: masters,
+		pattern: patternEscaper.Replace(c.getKey(prefix)) + "*",
+		err:     err,
+	}
+}
+
+// keyIterator chains SCAN of masters and strips the key prefix of keys
+type keyIterator struct {
+	c       *redisCache
+	ctx     context.Context
+	masters []redisv8.UniversalClient
+	pattern string
+	it      *redisv8.ScanIterator
+	key     string
+	err     error
+}
+
+func (it *keyIterator) Next() bool {
+	for it.err == nil {
+		if it.it == nil {
+			if len(it.masters) == 0 {
+				return false
+			}
+			it.it = it.masters[0].Scan(it.ctx, 0, it.pattern, int64(it.c.flushBatchSize())).Iterator()
+			it.masters = it.masters[1:]
+		}
+
+		if !it.it.Next(it.ctx) {
+			it.err = it.c.wrapErr(it.it.Err())
+			it.it = nil
+			continue
+		}
+
+		it.key = it.it.Val()
+
+		// Load lock keys of LockLoad are not cached keys
+		if it.c.cf.LockLoad && strings.HasSuffix(it.key, lockSuffix) {
+			continue
+		}
+		if it.c.keyPrefix != "" {
+			it.key = strings.TrimPrefix(it.key, it.c.keyPrefix+":")
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (it *keyIterator) Key() string {
+	return it.key
+}
+
+func (it *keyIterator) Err() error {
+	return it.err
+}
+
+func (c *redisCache) flushBatchSize() int {
+	if c.cf.FlushBatchSize > 0 {
+		return c.cf.FlushBatchSize
+	}
+
+	return defaultFlushBatchSize
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		StaleTTL: 10 * time.Second,
+	}, "test")
+	var loadCount int32
+	loader := func() (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return int(atomic.AddInt32(&loadCount, 1)), nil
+	}
+
+	cacheInt := 0
+	err := c.GetOrLoad("test:stale", &cacheInt, 1, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Redis keeps the value for TTL + StaleTTL
+	assert.Equal(t, 11*time.Second, s.TTL("test:test:stale"))
+
+	// Stale value is a cache miss for Lookup
+	time.Sleep(1100 * time.Millisecond)
+	found, err := c.Lookup("test:stale", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+
+	// Stale value is served without waiting for a single refresh
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		err = c.GetOrLoad("test:stale", &cacheInt, 1, loader)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, cacheInt)
+		assert.True(t, time.Since(start) < 50*time.Millisecond)
+	}
+
+	// Refreshed value
+	assert.Eventually(t, func() bool {
+		found, err := c.Lookup("test:stale", &cacheInt)
+		return found && err == nil && cacheInt == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loadCount))
+}
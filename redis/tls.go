@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+type TLSConfig struct {
+	Enable             bool
+	CAFile             string // PEM CA bundle to verify servers, default: system roots
+	CertFile           string // PEM client certificate for mutual TLS
+	KeyFile            string // PEM client key for mutual TLS
+	ServerName         string // Server name to verify, default: host of the address
+	InsecureSkipVerify bool   // Skip server verification, for testing only
+}
+
+// config loads files of cf, nil if TLS is disabled
+func (cf TLSConfig) config() (*tls.Config, error) {
+	if !cf.Enable {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cf.ServerName,
+		InsecureSkipVerify: cf.InsecureSkipVerify,
+	}
+
+	if cf.CAFile != "" {
+		pem, err := os.ReadFile(cf.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cf.CAFile)
+		}
+	}
+
+	if cf.CertFile != "" || cf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cf.CertFile, cf.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
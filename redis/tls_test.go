@@ -0,0 +1,181 @@
+package redis
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCert(t, dir, "ca", nil, nil)
+	server, serverKey := newCert(t, dir, "server", ca, caKey)
+	newCert(t, dir, "client", ca, caKey)
+
+	serverCert, err := tls.X509KeyPair(server, serverKey)
+	assert.Nil(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(ca)
+
+	s, err := miniredis.RunTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	assert.Nil(t, err)
+	t.Cleanup(s.Close)
+
+	newCache := func(cf TLSConfig) cache.Cache {
+		cf.Enable = true
+		return New(Config{Enable: true, Endpoint: s.Addr(), Timeout: 1, TLS: cf}, "test")
+	}
+
+	// Server is verified by CA, client is verified by its certificate
+	c := newCache(TLSConfig{
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		CertFile:   filepath.Join(dir, "client.pem"),
+		KeyFile:    filepath.Join(dir, "client-key.pem"),
+		ServerName: "localhost",
+	})
+	assert.True(t, c.IsReady())
+	err = c.Set("tls", 1, 0)
+	assert.Nil(t, err)
+	assert.True(t, s.Exists("test:tls"))
+
+	// Server is not verified
+	c = newCache(TLSConfig{
+		CertFile:           filepath.Join(dir, "client.pem"),
+		KeyFile:            filepath.Join(dir, "client-key.pem"),
+		InsecureSkipVerify: true,
+	})
+	assert.True(t, c.IsReady())
+
+	// Unknown server name
+	c = newCache(TLSConfig{
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		CertFile:   filepath.Join(dir, "client.pem"),
+		KeyFile:    filepath.Join(dir, "client-key.pem"),
+		ServerName: "other",
+	})
+	assert.False(t, c.IsReady())
+
+	// Client certificate is required
+	c = newCache(TLSConfig{
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		ServerName: "localhost",
+	})
+	assert.False(t, c.IsReady())
+
+	// Invalid config is returned by commands
+	c = newCache(TLSConfig{CAFile: filepath.Join(dir, "not-found.pem")})
+	assert.False(t, c.IsReady())
+	err = c.Set("tls", 1, 0)
+	assert.True(t, cache.IsBackendError(err))
+	assert.Contains(t, err.Error(), "invalid redis config")
+}
+
+func TestAuth(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.RequireUserAuth("app", "secret")
+
+	newCache := func(cf Config) cache.Cache {
+		cf.Enable = true
+		cf.Endpoint = s.Addr()
+		cf.Timeout = 1
+		cf.Username = "app"
+		return New(cf, "test")
+	}
+
+	assert.True(t, newCache(Config{Password: "secret"}).IsReady())
+	assert.False(t, newCache(Config{Password: "wrong"}).IsReady())
+
+	// Password is read from a secret file without trailing new line
+	file := filepath.Join(t.TempDir(), "password")
+	assert.Nil(t, os.WriteFile(file, []byte("secret\n"), 0600))
+	assert.True(t, newCache(Config{PasswordFile: file}).IsReady())
+
+	// Password is read from an environment variable
+	t.Setenv("TEST_REDIS_PASSWORD", "secret")
+	assert.True(t, newCache(Config{PasswordEnv: "TEST_REDIS_PASSWORD"}).IsReady())
+	assert.False(t, newCache(Config{PasswordEnv: "TEST_REDIS_PASSWORD_NOT_SET"}).IsReady())
+}
+
+func TestDB(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		DB:       1,
+	}, "test")
+
+	err := c.Set("db", 1, 0)
+	assert.Nil(t, err)
+	assert.False(t, s.Exists("test:db"))
+	assert.True(t, s.DB(1).Exists("test:db"))
+
+	// Cluster only has DB 0
+	c = New(Config{
+		Enable:   true,
+		Topology: Cluster,
+		Addrs:    []string{s.Addr()},
+		Timeout:  1,
+		DB:       1,
+	}, "test")
+	assert.False(t, c.IsReady())
+}
+
+// newCert writes a PEM certificate and key of name to dir, it is self-signed CA if parent is nil
+func newCert(t *testing.T, dir string, name string, parent []byte, parentKey []byte) (certPEM []byte, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer, signer := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		parentPair, err := tls.X509KeyPair(parent, parentKey)
+		assert.Nil(t, err)
+		issuer, err = x509.ParseCertificate(parentPair.Certificate[0])
+		assert.Nil(t, err)
+		signer = parentPair.PrivateKey.(*ecdsa.PrivateKey)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM, 0600))
+
+	return certPEM, keyPEM
+}
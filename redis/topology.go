@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	redisv8 "github.com/go-redis/redis/v8"
+)
+
+// Topology of Redis servers
+type Topology int
+
+const (
+	Standalone Topology = iota // Single node at Endpoint
+	Sentinel                   // Master of MasterName monitored by sentinels at Addrs
+	Cluster                    // Cluster discovered from seed nodes at Addrs
+)
+
+// newClient connects to the topology of cf, an invalid config fails all connections with its error
+func newClient(cf Config) redisv8.UniversalClient {
+	var dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+	password, tlsConfig, err := cf.credentials()
+	if err != nil {
+		err = fmt.Errorf("invalid redis config: %w", err)
+		dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, err
+		}
+	}
+
+	opt := &redisv8.UniversalOptions{
+		Addrs:              cf.addrs(),
+		MasterName:         cf.MasterName,
+		Dialer:             dialer,
+		Username:           cf.Username,
+		Password:           password,
+		DB:                 cf.DB,
+		MaxRetries:         -1, // Only idempotent commands are retried, see retry
+		DialTimeout:        cf.dialTimeout(),
+		ReadTimeout:        cf.readTimeout(),
+		WriteTimeout:       cf.writeTimeout(),
+		PoolSize:           cf.PoolSize,
+		MinIdleConns:       cf.MinIdleConns,
+		MaxConnAge:         cf.MaxConnAge,
+		PoolTimeout:        cf.PoolTimeout,
+		IdleTimeout:        cf.IdleTimeout,
+		IdleCheckFrequency: cf.IdleCheckFrequency,
+		TLSConfig:          tlsConfig,
+	}
+
+	switch cf.Topology {
+	case Sentinel:
+		return redisv8.NewFailoverClient(opt.Failover())
+	case Cluster:
+		return redisv8.NewClusterClient(opt.Cluster())
+	}
+
+	return redisv8.NewClient(opt.Simple())
+}
+
+// masters returns the nodes to scan, a standalone or sentinel client is the only master
+func (c *redisCache) masters(ctx context.Context) ([]redisv8.UniversalClient, error) {
+	cluster, ok := c.cacheEngine.(*redisv8.ClusterClient)
+	if !ok {
+		return []redisv8.UniversalClient{c.cacheEngine}, nil
+	}
+
+	var mu sync.Mutex
+	var masters []redisv8.UniversalClient
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redisv8.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		masters = append(masters, client)
+		return nil
+	})
+
+	return masters, c.wrapErr(err)
+}
+
+// crossSlot is true if keys of a multi-key command can be in different cluster slots,
+// such commands are split into single-key commands of a pipeline
+func (c *redisCache) crossSlot() bool {
+	return c.cf.Topology == Cluster && !c.cf.HashTag
+}
+
+// mget gets values of keys by one MGET, by a pipeline of GET if keys can be in different slots
+func (c *redisCache) mget(ctx context.Context, keys []string) ([]interface{}, error) {
+	if !c.crossSlot() {
+		return c.cacheEngine.MGet(ctx, keys...).Result()
+	}
+
+	cmds, _ := c.cacheEngine.Pipelined(ctx, func(pipe redisv8.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Get(ctx, key)
+		}
+
+		return nil
+	})
+
+	values := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		v, err := cmd.(*redisv8.StringCmd).Result()
+		switch {
+		case err == redisv8.Nil:
+			continue
+		case err != nil:
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// countKeys runs a multi-key command of client with keys, e.g. redisv8.Cmdable.Del,
+// by a pipeline of single-key commands if keys can be in different slots, count is the sum of results
+func (c *redisCache) countKeys(ctx context.Context, client redisv8.UniversalClient, keys []string, cmd func(redisv8.Cmdable, context.Context, ...string) *redisv8.IntCmd) (count int, err error) {
+	if !c.crossSlot() {
+		n, err := cmd(client, ctx, keys...).Result()
+		return int(n), err
+	}
+
+	cmds, err := client.Pipelined(ctx, func(pipe redisv8.Pipeliner) error {
+		for _, key := range keys {
+			cmd(pipe, ctx, key)
+		}
+
+		return nil
+	})
+	for _, cmd := range cmds {
+		count += int(cmd.(*redisv8.IntCmd).Val())
+	}
+
+	return count, err
+}
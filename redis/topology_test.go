@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	assert.IsType(t, &redisv8.Client{}, newClient(Config{Endpoint: "localhost:6379"}))
+	assert.IsType(t, &redisv8.Client{}, newClient(Config{Topology: Sentinel, MasterName: "master", Addrs: []string{"localhost:26379"}}))
+	assert.IsType(t, &redisv8.ClusterClient{}, newClient(Config{Topology: Cluster, Addrs: []string{"localhost:7000", "localhost:7001"}}))
+
+	// Endpoint is the default address
+	assert.Equal(t, []string{"localhost:6379"}, Config{Endpoint: "localhost:6379"}.addrs())
+}
+
+func TestCluster(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Topology: Cluster,
+		Addrs:    []string{s.Addr()},
+		Timeout:  1,
+	}, "test")
+	assert.True(t, c.IsReady())
+
+	// Multi-key commands are split by key
+	err := c.SetMany(map[string]interface{}{"key:1": 1, "key:2": 2}, 0)
+	assert.Nil(t, err)
+
+	found, err := c.GetMany([]string{"key:1", "key:2", "key:3"}, func() interface{} { return new(int) })
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, 2, *found["key:2"].(*int))
+
+	count, err := c.DeleteMany([]string{"key:1", "key:3"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// Single-key scripts and transactions
+	value, err := c.Incr("counter", 2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), value)
+
+	ok, err := c.CompareAndSwap("key:2", 2, 3, 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// Masters are scanned
+	var keys []string
+	it := c.Keys("")
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.Nil(t, it.Err())
+	assert.ElementsMatch(t, []string{"key:2", "counter"}, keys)
+
+	count, err = c.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Empty(t, s.Keys())
+}
+
+func TestHashTag(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Topology: Cluster,
+		Addrs:    []string{s.Addr()},
+		Timeout:  1,
+		HashTag:  true,
+	}, "test")
+
+	err := c.SetMany(map[string]interface{}{"key:1": 1, "key:2": 2}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"{test}:key:1", "{test}:key:2"}, s.Keys())
+
+	found, err := c.GetMany([]string{"key:1", "key:2"}, func() interface{} { return new(int) })
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+
+	// Keys are returned without the key prefix
+	var keys []string
+	it := c.Keys("key:")
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.Nil(t, it.Err())
+	assert.ElementsMatch(t, []string{"key:1", "key:2"}, keys)
+
+	count, err := c.DeleteByPrefix("key:")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Empty(t, s.Keys())
+}
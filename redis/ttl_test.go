@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoaitan/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTL(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:     true,
+		Endpoint:   s.Addr(),
+		Timeout:    1,
+		DefaultTTL: 60,
+	}, "test")
+
+	err := c.Set("test:ttl", 1, 10)
+	assert.Nil(t, err)
+
+	ttl, err := c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, ttl)
+
+	// Touch with default TTL
+	ok, err := c.Touch("test:ttl", -1)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 60*time.Second, s.TTL("test:test:ttl"))
+
+	// Expired key
+	s.FastForward(60 * time.Second)
+	_, err = c.TTL("test:ttl")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	ok, err = c.Touch("test:ttl", 10)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestTTL_Milliseconds(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := cache.WithContext(New(Config{
+		Enable:            true,
+		Endpoint:          s.Addr(),
+		ConnTimeout:       time.Second,
+		DefaultTTL:        60,
+		DefaultExpiration: 1500 * time.Millisecond,
+	}, "test"))
+	ctx := context.Background()
+
+	// DefaultExpiration overrides DefaultTTL
+	err := c.SetCtx(ctx, "test:ttl", 1, cache.DefaultTTL)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500*time.Millisecond, s.TTL("test:test:ttl"))
+
+	err = c.SetCtx(ctx, "test:ttl", 1, 250*time.Millisecond)
+	assert.Nil(t, err)
+
+	ttl, err := c.TTLCtx(ctx, "test:ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, ttl)
+
+	ok, err := c.TouchCtx(ctx, "test:ttl", 500*time.Millisecond)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 500*time.Millisecond, s.TTL("test:test:ttl"))
+
+	s.FastForward(500 * time.Millisecond)
+	_, err = c.TTLCtx(ctx, "test:ttl")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	// No expiration
+	err = c.SetCtx(ctx, "test:ttl", 1, cache.NoExpiration)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), s.TTL("test:test:ttl"))
+}
+
+func TestTTL_StaleWhileRevalidate(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := New(Config{
+		Enable:   true,
+		Endpoint: s.Addr(),
+		Timeout:  1,
+		StaleTTL: 10 * time.Second,
+	}, "test")
+
+	// TTL is the remaining fresh time, not the Redis expiration
+	err := c.Set("test:ttl", 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 11*time.Second, s.TTL("test:test:ttl"))
+
+	ttl, err := c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Second, "ttl: %s", ttl)
+
+	ok, err := c.IsExist("test:ttl")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// Touch extends fresh time and Redis expiration
+	ok, err = c.Touch("test:ttl", 20)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, s.TTL("test:test:ttl"))
+
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 19*time.Second && ttl <= 20*time.Second, "ttl: %s", ttl)
+
+	// Stale value is missing
+	err = c.Set("test:ttl", 1, 1)
+	assert.Nil(t, err)
+	time.Sleep(1100 * time.Millisecond)
+
+	_, err = c.TTL("test:ttl")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	ok, err = c.IsExist("test:ttl")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	ok, err = c.Touch("test:ttl", 20)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,25 +22,80 @@ var (
 	}
 )
 
-func GetTestSuite(isEnableCache bool) []func(t *testing.T, c cache.Cache) {
+// Jitter controls the random source of TTL jitter configured in the tested cache
+type Jitter struct {
+	Max time.Duration
+	rnd atomic.Value
+}
+
+// NewJitter with max absolute jitter, it should be longer than 2 seconds
+func NewJitter(max time.Duration) *Jitter {
+	j := &Jitter{Max: max}
+	j.rnd.Store(0.0)
+
+	return j
+}
+
+// Config returns the jitter to configure in the tested cache
+func (j *Jitter) Config() cache.Jitter {
+	return cache.Jitter{
+		Max:  j.Max,
+		Rand: j.Rand,
+	}
+}
+
+// Rand is the random source controlled by the test suite
+func (j *Jitter) Rand() float64 {
+	return j.rnd.Load().(float64)
+}
+
+func (j *Jitter) setRand(rnd float64) {
+	j.rnd.Store(rnd)
+}
+
+// GetTestSuite returns tests of a cache, jitter is given if TTL jitter is configured in the cache
+func GetTestSuite(isEnableCache bool, jitter ...*Jitter) []func(t *testing.T, c cache.Cache) {
 	// Test suite for enable cache
 	if isEnableCache {
-		return []func(t *testing.T, c cache.Cache){
+		suite := []func(t *testing.T, c cache.Cache){
 			testSet,
 			testGet,
+			testLookup,
+			testGetOrLoad,
 			testDelete,
 			testIsExist,
+			testMany,
+			testTTL,
+			testIncr,
+			testConditional,
+			testDeleteByPrefix,
+			testKeys,
 			testFlush,
+			testContext,
+		}
+		for _, j := range jitter {
+			suite = append(suite, testJitter(j))
 		}
+
+		return suite
 	}
 
 	// Test suite for disable cache
 	return []func(t *testing.T, c cache.Cache){
 		testDisableCacheSet,
 		testDisableCacheGet,
+		testDisableCacheLookup,
+		testDisableCacheGetOrLoad,
 		testDisableCacheDelete,
 		testDisableCacheIsExist,
+		testDisableCacheMany,
+		testDisableCacheTTL,
+		testDisableCacheIncr,
+		testDisableCacheConditional,
+		testDisableCacheDeleteByPrefix,
+		testDisableCacheKeys,
 		testDisableCacheFlush,
+		testDisableCacheContext,
 	}
 }
 
@@ -100,6 +158,90 @@ func testGet(t *testing.T, c cache.Cache) {
 	assert.NotEqual(t, missCacheErr, err)
 }
 
+func testLookup(t *testing.T, c cache.Cache) {
+	// Missing cache doesn't touch ptr
+	cacheInt := -1
+	found, err := c.Lookup("test:lookup:missing", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, cacheInt)
+
+	// Found cache, including zero value
+	c.Set("test:lookup", 0, 0)
+
+	found, err = c.Lookup("test:lookup", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cacheInt)
+
+	// Invalid type between set and lookup cache
+	cacheString := ""
+	found, err = c.Lookup("test:lookup", &cacheString)
+	assert.True(t, found)
+	assert.Error(t, err)
+
+	// Deleted cache
+	c.Delete("test:lookup")
+
+	found, err = c.Lookup("test:lookup", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+}
+
+func testGetOrLoad(t *testing.T, c cache.Cache) {
+	loadCount := 0
+	loader := func() (interface{}, error) {
+		loadCount++
+		return 1, nil
+	}
+
+	// Load missing cache
+	cacheInt := 0
+	err := c.GetOrLoad("test:get-or-load", &cacheInt, 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+	assert.Equal(t, 1, loadCount)
+
+	// Loaded value is cached
+	cacheInt = 0
+	found, err := c.Lookup("test:get-or-load", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Loader is skipped for cached value
+	cacheInt = 0
+	err = c.GetOrLoad("test:get-or-load", &cacheInt, 0, loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+	assert.Equal(t, 1, loadCount)
+
+	// Without loader, cached value is decoded and missing cache is ErrMiss
+	cacheInt = 0
+	err = c.GetOrLoad("test:get-or-load", &cacheInt, 0, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	err = c.GetOrLoad("test:get-or-load:nil", &cacheInt, 0, nil)
+	assert.Equal(t, cache.ErrMiss, err)
+
+	// Loader error is returned and nothing is cached
+	err = c.GetOrLoad("test:get-or-load:error", &cacheInt, 0, func() (interface{}, error) {
+		return nil, missCacheErr
+	})
+	assert.Equal(t, missCacheErr, err)
+
+	ok, err := c.IsExist("test:get-or-load:error")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Invalid type between loaded value and ptr
+	cacheString := ""
+	err = c.GetOrLoad("test:get-or-load:invalid", &cacheString, 0, loader)
+	assert.Error(t, err)
+	assert.NotEqual(t, missCacheErr, err)
+}
+
 func testDelete(t *testing.T, c cache.Cache) {
 	// Not found key
 	ok, err := c.Delete("test:delete:not-found")
@@ -133,9 +275,317 @@ func testIsExist(t *testing.T, c cache.Cache) {
 	assert.False(t, ok)
 	assert.Nil(t, err)
 }
+
+func testMany(t *testing.T, c cache.Cache) {
+	newInt := func() interface{} { return new(int) }
+
+	// Set many keys
+	err := c.SetMany(map[string]interface{}{"test:many:1": 1, "test:many:2": 2}, 0)
+	assert.Nil(t, err)
+
+	// Get found keys only
+	found, err := c.GetMany([]string{"test:many:1", "test:many:2", "test:many:not-found"}, newInt)
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, 1, *found["test:many:1"].(*int))
+	assert.Equal(t, 2, *found["test:many:2"].(*int))
+
+	// Only missing keys are loaded at once
+	loadCount := 0
+	loader := func(keys []string) (map[string]interface{}, error) {
+		loadCount++
+		assert.Equal(t, []string{"test:many:3", "test:many:4"}, keys)
+		return map[string]interface{}{"test:many:3": 3}, nil
+	}
+	found, err = c.GetManyOrLoad([]string{"test:many:1", "test:many:3", "test:many:4"}, newInt, 0, loader)
+	assert.Nil(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, 1, *found["test:many:1"].(*int))
+	assert.Equal(t, 3, *found["test:many:3"].(*int))
+	assert.Equal(t, 1, loadCount)
+
+	// Loaded values are cached
+	cacheInt := 0
+	ok, err := c.Lookup("test:many:3", &cacheInt)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, cacheInt)
+
+	// Loader error is returned with found values
+	found, err = c.GetManyOrLoad([]string{"test:many:1", "test:many:error"}, newInt, 0, func(keys []string) (map[string]interface{}, error) {
+		return nil, missCacheErr
+	})
+	assert.Equal(t, missCacheErr, err)
+	assert.Len(t, found, 1)
+
+	// Delete many keys
+	count, err := c.DeleteMany([]string{"test:many:1", "test:many:2", "test:many:3", "test:many:not-found"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	found, err = c.GetMany([]string{"test:many:1", "test:many:2", "test:many:3"}, newInt)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	// Empty keys
+	found, err = c.GetMany(nil, newInt)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	count, err = c.DeleteMany(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testTTL(t *testing.T, c cache.Cache) {
+	// Not found key
+	_, err := c.TTL("test:ttl:not-found")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	ok, err := c.Touch("test:ttl:not-found", 10)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Never expire
+	c.Set("test:ttl", 1, 0)
+	ttl, err := c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	// Expire
+	c.Set("test:ttl", 1, 10)
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 8*time.Second && ttl <= 10*time.Second, "ttl: %s", ttl)
+
+	// Touch extends TTL without rewriting value
+	ok, err = c.Touch("test:ttl", 20)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 18*time.Second && ttl <= 20*time.Second, "ttl: %s", ttl)
+
+	cacheInt := 0
+	found, err := c.Lookup("test:ttl", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	// Touch removes expiration
+	ok, err = c.Touch("test:ttl", 0)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	// Sub-second TTL of ContextCache never becomes no expiration
+	cc := cache.WithContext(c)
+	err = cc.SetCtx(context.Background(), "test:ttl", 1, 1500*time.Millisecond)
+	assert.Nil(t, err)
+
+	ttl, err = c.TTL("test:ttl")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= 2*time.Second, "ttl: %s", ttl)
+
+	c.Delete("test:ttl")
+}
+
+func testIncr(t *testing.T, c cache.Cache) {
+	c.Delete("test:incr")
+
+	// Missing counter is created with ttl
+	value, err := c.Incr("test:incr", 2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), value)
+
+	ttl, err := c.TTL("test:incr")
+	assert.Nil(t, err)
+	assert.True(t, ttl > 8*time.Second && ttl <= 10*time.Second, "ttl: %s", ttl)
+
+	// Concurrent increments are atomic
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Incr("test:incr", 1, 20)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	value, err = c.Decr("test:incr", 12, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(40), value)
+
+	// TTL is not extended by later increments
+	ttl, err = c.TTL("test:incr")
+	assert.Nil(t, err)
+	assert.True(t, ttl <= 10*time.Second, "ttl: %s", ttl)
+
+	// Counter can be read by Get
+	var cacheInt int64
+	found, err := c.Lookup("test:incr", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(40), cacheInt)
+
+	// Value which is not a counter
+	c.Set("test:incr:invalid", "abc", 0)
+	_, err = c.Incr("test:incr:invalid", 1, 0)
+	assert.Error(t, err)
+
+	c.Delete("test:incr")
+	c.Delete("test:incr:invalid")
+}
+
+func testConditional(t *testing.T, c cache.Cache) {
+	c.Delete("test:set-nx")
+	c.Delete("test:cas")
+
+	// Only one of concurrent SetNX sets the key
+	var wg sync.WaitGroup
+	var setCount int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := c.SetNX("test:set-nx", i, 0)
+			assert.Nil(t, err)
+			if ok {
+				atomic.AddInt32(&setCount, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), setCount)
+
+	ok, err := c.SetNX("test:set-nx", 100, 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Missing key or different value is not swapped
+	ok, err = c.CompareAndSwap("test:cas", 0, 1, 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	c.Set("test:cas", 0, 0)
+	ok, err = c.CompareAndSwap("test:cas", 1, 2, 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Concurrent read-modify-write cycles don't lose updates
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				cacheInt := 0
+				if _, err := c.Lookup("test:cas", &cacheInt); err != nil {
+					assert.Nil(t, err)
+					return
+				}
+
+				ok, err := c.CompareAndSwap("test:cas", cacheInt, cacheInt+1, 0)
+				if err != nil {
+					assert.Nil(t, err)
+					return
+				}
+				if ok {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	cacheInt := 0
+	found, err := c.Lookup("test:cas", &cacheInt)
+	assert.True(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, cacheInt)
+
+	c.Delete("test:set-nx")
+	c.Delete("test:cas")
+}
+
+func testDeleteByPrefix(t *testing.T, c cache.Cache) {
+	err := c.SetMany(map[string]interface{}{
+		"test:prefix:user:1:name":    "name",
+		"test:prefix:user:1:profile": "profile",
+		"test:prefix:user:10:name":   "other",
+		"test:prefix:user:*:name":    "glob",
+	}, 0)
+	assert.Nil(t, err)
+
+	// Only keys starting with prefix are deleted
+	count, err := c.DeleteByPrefix("test:prefix:user:1:")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	ok, err := c.IsExist("test:prefix:user:1:name")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	ok, err = c.IsExist("test:prefix:user:10:name")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// Prefix is matched literally
+	count, err = c.DeleteByPrefix("test:prefix:user:*")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	ok, err = c.IsExist("test:prefix:user:10:name")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	count, err = c.DeleteByPrefix("test:prefix:")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = c.DeleteByPrefix("test:prefix:")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testKeys(t *testing.T, c cache.Cache) {
+	err := c.SetMany(map[string]interface{}{
+		"test:keys:1":     1,
+		"test:keys:2":     2,
+		"test:keys:[3]":   3,
+		"test:keys-other": 4,
+	}, 0)
+	assert.Nil(t, err)
+
+	keys, err := collectKeys(c.Keys("test:keys:"))
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"test:keys:1", "test:keys:2", "test:keys:[3]"}, keys)
+
+	// Prefix is matched literally
+	keys, err = collectKeys(c.Keys("test:keys:["))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"test:keys:[3]"}, keys)
+
+	keys, err = collectKeys(c.Keys("test:keys:not-found"))
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	// Deleted keys are not iterated
+	_, err = c.DeleteMany([]string{"test:keys:1", "test:keys:2", "test:keys:[3]", "test:keys-other"})
+	assert.Nil(t, err)
+
+	keys, err = collectKeys(c.Keys("test:keys"))
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
 func testFlush(t *testing.T, c cache.Cache) {
 	c.Set("test:flush", 1, 0)
-	_, err := c.Flush()
+	count, err := c.Flush()
 
 	// Flush function can be not supported in Redis implementation
 	if err == cache.NotSupportedErr {
@@ -143,12 +593,76 @@ func testFlush(t *testing.T, c cache.Cache) {
 		return
 	}
 	assert.Nil(t, err)
+	assert.True(t, count > 0)
 
 	ok, err := c.IsExist("test:flush")
 	assert.False(t, ok)
 	assert.Nil(t, err)
 }
 
+type ctxKey struct{}
+
+func testContext(t *testing.T, c cache.Cache) {
+	cc := cache.WithContext(c)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// Miss cache function receives the same context
+	err := cc.GetCtx(ctx, "test:ctx:missing", nil, func(ctx context.Context) error {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return missCacheErr
+	})
+	assert.Equal(t, missCacheErr, err)
+
+	// Set and get with context
+	err = cc.SetCtx(ctx, "test:ctx", 1, cache.NoExpiration)
+	assert.Nil(t, err)
+
+	cacheInt := 0
+	err = cc.GetCtx(ctx, "test:ctx", &cacheInt, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	ok, err := cc.IsExistCtx(ctx, "test:ctx")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	ok, err = cc.DeleteCtx(ctx, "test:ctx")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	assert.True(t, cc.IsReadyCtx(ctx))
+}
+
+func testJitter(j *Jitter) func(t *testing.T, c cache.Cache) {
+	return func(t *testing.T, c cache.Cache) {
+		// No jitter
+		j.setRand(0)
+		err := c.Set("test:jitter:min", 1, 1)
+		assert.Nil(t, err)
+
+		// Max jitter
+		j.setRand(0.999)
+		err = c.Set("test:jitter:max", 1, 1)
+		assert.Nil(t, err)
+		j.setRand(0)
+
+		time.Sleep(time.Second * 2)
+		ok, err := c.IsExist("test:jitter:min")
+		assert.False(t, ok)
+		assert.Nil(t, err)
+
+		ok, err = c.IsExist("test:jitter:max")
+		assert.True(t, ok)
+		assert.Nil(t, err)
+
+		// Jitter is added up to max
+		time.Sleep(j.Max)
+		ok, err = c.IsExist("test:jitter:max")
+		assert.False(t, ok)
+		assert.Nil(t, err)
+	}
+}
+
 // Disable cache tests
 func testDisableCacheSet(t *testing.T, c cache.Cache) {
 	// Set empty key
@@ -162,6 +676,34 @@ func testDisableCacheGet(t *testing.T, c cache.Cache) {
 	assert.Equal(t, missCacheErr, err)
 }
 
+func testDisableCacheLookup(t *testing.T, c cache.Cache) {
+	c.Set("test:lookup:disable", 1, 0)
+
+	cacheInt := -1
+	found, err := c.Lookup("test:lookup:disable", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, cacheInt)
+}
+
+func testDisableCacheGetOrLoad(t *testing.T, c cache.Cache) {
+	// Loaded value is decoded into ptr without caching
+	cacheInt := 0
+	err := c.GetOrLoad("test:get-or-load:disable", &cacheInt, 0, func() (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cacheInt)
+
+	found, err := c.Lookup("test:get-or-load:disable", &cacheInt)
+	assert.False(t, found)
+	assert.Nil(t, err)
+
+	// Without loader, it is always ErrMiss
+	err = c.GetOrLoad("test:get-or-load:disable", &cacheInt, 0, nil)
+	assert.Equal(t, cache.ErrMiss, err)
+}
+
 func testDisableCacheDelete(t *testing.T, c cache.Cache) {
 	ok, err := c.Delete("test:delete:disable")
 	assert.False(t, ok)
@@ -174,6 +716,76 @@ func testDisableCacheIsExist(t *testing.T, c cache.Cache) {
 	assert.False(t, ok)
 	assert.Nil(t, err)
 }
+
+func testDisableCacheMany(t *testing.T, c cache.Cache) {
+	newInt := func() interface{} { return new(int) }
+
+	err := c.SetMany(map[string]interface{}{"test:many:disable": 1}, 0)
+	assert.Nil(t, err)
+
+	// Loaded values are decoded without caching
+	found, err := c.GetManyOrLoad([]string{"test:many:disable"}, newInt, 0, func(keys []string) (map[string]interface{}, error) {
+		return map[string]interface{}{"test:many:disable": 1}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, *found["test:many:disable"].(*int))
+
+	found, err = c.GetMany([]string{"test:many:disable"}, newInt)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	count, err := c.DeleteMany([]string{"test:many:disable"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testDisableCacheTTL(t *testing.T, c cache.Cache) {
+	c.Set("test:ttl:disable", 1, 10)
+
+	_, err := c.TTL("test:ttl:disable")
+	assert.Equal(t, cache.ErrMiss, err)
+
+	ok, err := c.Touch("test:ttl:disable", 10)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func testDisableCacheIncr(t *testing.T, c cache.Cache) {
+	value, err := c.Incr("test:incr:disable", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), value)
+
+	value, err = c.Decr("test:incr:disable", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), value)
+}
+
+func testDisableCacheConditional(t *testing.T, c cache.Cache) {
+	ok, err := c.SetNX("test:set-nx:disable", 1, 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	ok, err = c.CompareAndSwap("test:set-nx:disable", 1, 2, 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func testDisableCacheDeleteByPrefix(t *testing.T, c cache.Cache) {
+	c.Set("test:prefix:disable", 1, 0)
+
+	count, err := c.DeleteByPrefix("test:prefix:")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testDisableCacheKeys(t *testing.T, c cache.Cache) {
+	c.Set("test:keys:disable", 1, 0)
+
+	keys, err := collectKeys(c.Keys("test:keys:"))
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
 func testDisableCacheFlush(t *testing.T, c cache.Cache) {
 	count, err := c.Flush()
 
@@ -185,3 +797,28 @@ func testDisableCacheFlush(t *testing.T, c cache.Cache) {
 	assert.Nil(t, err)
 	assert.Equal(t, 0, count)
 }
+
+func testDisableCacheContext(t *testing.T, c cache.Cache) {
+	cc := cache.WithContext(c)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// Miss cache function receives the same context
+	err := cc.GetCtx(ctx, "test:ctx:disable", nil, func(ctx context.Context) error {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return missCacheErr
+	})
+	assert.Equal(t, missCacheErr, err)
+
+	// Nil miss cache function is skipped
+	err = cc.GetCtx(ctx, "test:ctx:disable", nil, nil)
+	assert.Nil(t, err)
+}
+
+// collectKeys of an iterator
+func collectKeys(it cache.Iterator) (keys []string, err error) {
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+
+	return keys, it.Err()
+}
@@ -0,0 +1,26 @@
+package cache
+
+import "time"
+
+const (
+	DefaultTTL   time.Duration = -1 // Use the default TTL of the cache config
+	NoExpiration time.Duration = 0  // Never expire
+)
+
+// Seconds converts ttl in seconds of the Cache interface, -1 is DefaultTTL and 0 is NoExpiration
+func Seconds(ttl int) time.Duration {
+	if ttl < 0 {
+		return DefaultTTL
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// ToSeconds converts ttl to seconds of the Cache interface, a positive ttl is rounded up so it never becomes NoExpiration
+func ToSeconds(ttl time.Duration) int {
+	if ttl < 0 {
+		return -1
+	}
+
+	return int((ttl + time.Second - 1) / time.Second)
+}
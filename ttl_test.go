@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeconds(t *testing.T) {
+	assert.Equal(t, DefaultTTL, Seconds(-1))
+	assert.Equal(t, NoExpiration, Seconds(0))
+	assert.Equal(t, 10*time.Second, Seconds(10))
+}
+
+func TestToSeconds(t *testing.T) {
+	assert.Equal(t, -1, ToSeconds(DefaultTTL))
+	assert.Equal(t, 0, ToSeconds(NoExpiration))
+	assert.Equal(t, 1, ToSeconds(time.Millisecond))
+	assert.Equal(t, 2, ToSeconds(1500*time.Millisecond))
+	assert.Equal(t, 10, ToSeconds(10*time.Second))
+}
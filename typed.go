@@ -0,0 +1,45 @@
+package cache
+
+// Typed is a type-safe wrapper of Cache, so the compiler enforces the value type of a keyspace
+type Typed[T any] struct {
+	c Cache
+}
+
+// NewTyped wraps c (local, redis, multi...) with value type T
+func NewTyped[T any](c Cache) *Typed[T] {
+	return &Typed[T]{c: c}
+}
+
+// Set value with TTL, see Cache.Set
+func (t *Typed[T]) Set(key string, v T, ttl int) error {
+	return t.c.Set(key, v, ttl)
+}
+
+// Get cached value, found=false on cache miss
+func (t *Typed[T]) Get(key string) (v T, found bool, err error) {
+	found, err = t.c.Lookup(key, &v)
+	if err != nil {
+		var zero T
+		return zero, found, err
+	}
+
+	return v, found, nil
+}
+
+// GetOrLoad returns cached value, on cache miss the value is loaded and cached with default TTL
+func (t *Typed[T]) GetOrLoad(key string, loader func() (T, error)) (v T, err error) {
+	err = t.c.GetOrLoad(key, &v, -1, func() (interface{}, error) {
+		return loader()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v, nil
+}
+
+// Delete cached value, see Cache.Delete
+func (t *Typed[T]) Delete(key string) (ok bool, err error) {
+	return t.c.Delete(key)
+}
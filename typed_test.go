@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedData struct {
+	Number int
+}
+
+func TestTyped(t *testing.T) {
+	c := NewTyped[typedData](mapCache{})
+
+	// Missing cache
+	v, found, err := c.Get("key")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, typedData{}, v)
+
+	// Found cache
+	err = c.Set("key", typedData{Number: 1}, 0)
+	assert.Nil(t, err)
+
+	v, found, err = c.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, typedData{Number: 1}, v)
+
+	// Deleted cache
+	ok, err := c.Delete("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, found, err = c.Get("key")
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestTyped_GetOrLoad(t *testing.T) {
+	c := NewTyped[typedData](mapCache{})
+	loadCount := 0
+	loader := func() (typedData, error) {
+		loadCount++
+		return typedData{Number: loadCount}, nil
+	}
+
+	// Load missing cache
+	v, err := c.GetOrLoad("key", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, typedData{Number: 1}, v)
+
+	// Cached value, loader is skipped
+	v, err = c.GetOrLoad("key", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, typedData{Number: 1}, v)
+	assert.Equal(t, 1, loadCount)
+
+	// Loader error is returned without setting cache
+	loadErr := fmt.Errorf("load error")
+	v, err = c.GetOrLoad("key:error", func() (typedData, error) {
+		return typedData{Number: 1}, loadErr
+	})
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, typedData{}, v)
+
+	_, found, _ := c.Get("key:error")
+	assert.False(t, found)
+}
+
+func TestTyped_InvalidType(t *testing.T) {
+	m := mapCache{}
+	err := m.Set("key", "string", 0)
+	assert.Nil(t, err)
+
+	v, found, err := NewTyped[int](m).Get("key")
+	assert.Error(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 0, v)
+}